@@ -2,6 +2,7 @@
 package jwz
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"math/big"
@@ -35,9 +36,17 @@ var (
 // nolint : used for init proving method instance
 func init() {
 	ProvingMethodGroth16AuthInstance = &ProvingMethodGroth16Auth{AuthGroth16Alg}
-	RegisterProvingMethod(ProvingMethodGroth16AuthInstance.ProvingMethodAlg, func() ProvingMethod {
+	err := RegisterProvingMethod(ProvingMethodGroth16AuthInstance.ProvingMethodAlg, func() ProvingMethod {
 		return ProvingMethodGroth16AuthInstance
 	})
+	if err != nil {
+		panic(err)
+	}
+	// auth v1 circuit is superseded by auth v2
+	err = DefaultRegistry.SetStatus(AuthGroth16Alg, MethodDeprecated)
+	if err != nil {
+		panic(err)
+	}
 }
 
 // Alg returns current zk alg
@@ -53,6 +62,17 @@ func (m *ProvingMethodGroth16Auth) CircuitID() string {
 // Verify performs Groth16 proof verification and checks equality of message hash and proven challenge public signals
 func (m *ProvingMethodGroth16Auth) Verify(messageHash []byte, proof *types.ZKProof, verificationKey []byte) error {
 
+	err := m.VerifyPubSignals(messageHash, proof)
+	if err != nil {
+		return err
+	}
+
+	return verifier.VerifyGroth16(*proof, verificationKey)
+}
+
+// VerifyPubSignals checks equality of message hash and proven challenge public signals
+func (m *ProvingMethodGroth16Auth) VerifyPubSignals(messageHash []byte, proof *types.ZKProof) error {
+
 	var outputs circuits.AuthPubSignals
 	pubBytes, err := json.Marshal(proof.PubSignals)
 	if err != nil {
@@ -67,12 +87,16 @@ func (m *ProvingMethodGroth16Auth) Verify(messageHash []byte, proof *types.ZKPro
 	if outputs.Challenge.Cmp(new(big.Int).SetBytes(messageHash)) != 0 {
 		return errors.New("challenge is not equal to message hash")
 	}
-
-	return verifier.VerifyGroth16(*proof, verificationKey)
+	return nil
 }
 
 // Prove generates proof using auth circuit and Groth16 alg, checks that proven message hash is set as a part of circuit specific inputs
 func (m *ProvingMethodGroth16Auth) Prove(inputs, provingKey, wasm []byte) (*types.ZKProof, error) {
+	return m.ProveContext(context.Background(), inputs, provingKey, wasm)
+}
+
+// ProveContext is Prove that stops before generating the proof if the context is done
+func (m *ProvingMethodGroth16Auth) ProveContext(ctx context.Context, inputs, provingKey, wasm []byte) (*types.ZKProof, error) {
 	calc, err := witness.NewCalculator(wasm,
 		witness.WithWasmEngine(wazero.NewCircom2WZWitnessCalculator))
 	if err != nil {
@@ -88,6 +112,10 @@ func (m *ProvingMethodGroth16Auth) Prove(inputs, provingKey, wasm []byte) (*type
 	if err != nil {
 		return nil, err
 	}
+	// witness calculation and proving can't be interrupted, so context is checked between them
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
 	return prover.Groth16Prover(provingKey, wtnsBytes)
 
 }
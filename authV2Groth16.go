@@ -1,6 +1,7 @@
 package jwz
 
 import (
+	"context"
 	"crypto/sha256"
 	"encoding/json"
 	"errors"
@@ -37,8 +38,11 @@ func init() {
 		ProvingMethodAlg: AuthV2Groth16Alg,
 		cache:            make(map[[sha256.Size]byte]witness.Calculator),
 	}
-	RegisterProvingMethod(ProvingMethodGroth16AuthV2Instance.ProvingMethodAlg,
+	err := RegisterProvingMethod(ProvingMethodGroth16AuthV2Instance.ProvingMethodAlg,
 		func() ProvingMethod { return ProvingMethodGroth16AuthV2Instance })
+	if err != nil {
+		panic(err)
+	}
 }
 
 // Alg returns current zk alg
@@ -54,6 +58,17 @@ func (m *ProvingMethodGroth16AuthV2) CircuitID() string {
 // Verify performs Groth16 proof verification and checks equality of message hash and proven challenge public signals
 func (m *ProvingMethodGroth16AuthV2) Verify(messageHash []byte, proof *types.ZKProof, verificationKey []byte) error {
 
+	err := m.VerifyPubSignals(messageHash, proof)
+	if err != nil {
+		return err
+	}
+
+	return verifier.VerifyGroth16(*proof, verificationKey)
+}
+
+// VerifyPubSignals checks equality of message hash and proven challenge public signals
+func (m *ProvingMethodGroth16AuthV2) VerifyPubSignals(messageHash []byte, proof *types.ZKProof) error {
+
 	var outputs circuits.AuthV2PubSignals
 	pubBytes, err := json.Marshal(proof.PubSignals)
 	if err != nil {
@@ -68,13 +83,17 @@ func (m *ProvingMethodGroth16AuthV2) Verify(messageHash []byte, proof *types.ZKP
 	if outputs.Challenge.Cmp(new(big.Int).SetBytes(messageHash)) != 0 {
 		return errors.New("challenge is not equal to message hash")
 	}
-
-	return verifier.VerifyGroth16(*proof, verificationKey)
+	return nil
 }
 
 // Prove generates proof using authV2 circuit and Groth16 alg,
 // checks that proven message hash is set as a part of circuit specific inputs
 func (m *ProvingMethodGroth16AuthV2) Prove(inputs, provingKey, wasm []byte) (*types.ZKProof, error) {
+	return m.ProveContext(context.Background(), inputs, provingKey, wasm)
+}
+
+// ProveContext is Prove that stops before generating the proof if the context is done
+func (m *ProvingMethodGroth16AuthV2) ProveContext(ctx context.Context, inputs, provingKey, wasm []byte) (*types.ZKProof, error) {
 
 	var calc witness.Calculator
 	var err error
@@ -94,6 +113,10 @@ func (m *ProvingMethodGroth16AuthV2) Prove(inputs, provingKey, wasm []byte) (*ty
 		return nil, err
 	}
 
+	// witness calculation and proving can't be interrupted, so context is checked between them
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
 	return prover.Groth16Prover(provingKey, wtnsBytes)
 }
 
@@ -0,0 +1,292 @@
+package jwz
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier/bn256"
+)
+
+// PubSignalsVerifier is implemented by groth16 proving methods which checks of public signals can be done
+// apart from the pairing check, so their proofs can be batch verified.
+type PubSignalsVerifier interface {
+	VerifyPubSignals(messageHash []byte, proof *types.ZKProof) error // Returns nil if public signals are valid
+}
+
+// batchRandomnessBits is size of random coefficients of linear combination,
+// probability to accept an invalid batch is 2^-128.
+const batchRandomnessBits = 128
+
+// groth16VK is groth16 verification key in bn256 format
+type groth16VK struct {
+	Alpha *bn256.G1
+	Beta  *bn256.G2
+	Gamma *bn256.G2
+	Delta *bn256.G2
+	IC    []*bn256.G1
+}
+
+// groth16VKJSON is groth16 verification key in snarkjs format
+type groth16VKJSON struct {
+	Alpha []string   `json:"vk_alpha_1"`
+	Beta  [][]string `json:"vk_beta_2"`
+	Gamma [][]string `json:"vk_gamma_2"`
+	Delta [][]string `json:"vk_delta_2"`
+	IC    [][]string `json:"IC"`
+}
+
+// groth16Proof is groth16 proof with public inputs in bn256 format
+type groth16Proof struct {
+	A, C   *bn256.G1
+	B      *bn256.G2
+	Inputs []*big.Int
+}
+
+// BatchVerify verifies groth16 proofs of tokens that share the verification key with a single multi-pairing
+// over random linear combination of the proofs. If the batch is invalid, proofs are checked one by one
+// to locate failures. Tokens which proving method doesn't implement PubSignalsVerifier are verified individually.
+// Per token results are returned even if some proofs are invalid.
+func BatchVerify(tokens []*Token, verificationKey []byte) ([]ProofVerificationResult, error) {
+	vk, err := parseGroth16VK(verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]ProofVerificationResult, len(tokens))
+	var batch []int
+	proofs := make([]*groth16Proof, len(tokens))
+	for i, token := range tokens {
+		results[i].Token = token
+		pv, ok := token.Method.(PubSignalsVerifier)
+		if !ok || token.ZkProof == nil || token.ZkProof.Proof == nil || token.ZkProof.Proof.Protocol != Groth16 {
+			_, results[i].Err = token.Verify(verificationKey)
+			continue
+		}
+		proofs[i], results[i].Err = token.prepareBatch(pv, vk)
+		if results[i].Err == nil {
+			batch = append(batch, i)
+		}
+	}
+
+	if len(batch) > 1 {
+		ok, err := batchPairingCheck(vk, proofs, batch)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			batch = nil
+		}
+	}
+	// single proof or invalid batch
+	for _, i := range batch {
+		if !groth16PairingCheck(vk, proofs[i]) {
+			results[i].Err = errors.New("iden3/go-jwz: invalid proof")
+		}
+	}
+
+	var invalid int
+	for _, r := range results {
+		if r.Err != nil {
+			invalid++
+		}
+	}
+	if invalid != 0 {
+		return results, fmt.Errorf("iden3/go-jwz: %d of %d proofs are invalid", invalid, len(tokens))
+	}
+	return results, nil
+}
+
+// prepareBatch performs all checks of the token except pairing check and returns parsed proof
+func (token *Token) prepareBatch(pv PubSignalsVerifier, vk *groth16VK) (*groth16Proof, error) {
+	msgHash, err := token.attachedMessageHash()
+	if err != nil {
+		return nil, err
+	}
+	err = token.checkMethod()
+	if err != nil {
+		return nil, err
+	}
+	err = token.validateCritical()
+	if err != nil {
+		return nil, err
+	}
+	err = pv.VerifyPubSignals(msgHash, token.ZkProof)
+	if err != nil {
+		return nil, err
+	}
+	return parseGroth16Proof(token.ZkProof, len(vk.IC)-1)
+}
+
+// batchPairingCheck checks that for random r_i
+// prod e(r_i*A_i, B_i) * e(-sum(r_i)*alpha, beta) * e(-sum(r_i*vkX_i), gamma) * e(-sum(r_i*C_i), delta) == 1
+func batchPairingCheck(vk *groth16VK, proofs []*groth16Proof, batch []int) (bool, error) {
+	g1 := make([]*bn256.G1, 0, len(batch)+3)
+	g2 := make([]*bn256.G2, 0, len(batch)+3)
+
+	rSum := new(big.Int)
+	icScalars := make([]*big.Int, len(vk.IC))
+	for j := range icScalars {
+		icScalars[j] = new(big.Int)
+	}
+	c := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+
+	limit := new(big.Int).Lsh(big.NewInt(1), batchRandomnessBits)
+	for _, i := range batch {
+		r, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return false, err
+		}
+		r.Add(r, big.NewInt(1))
+
+		p := proofs[i]
+		g1 = append(g1, new(bn256.G1).ScalarMult(p.A, r))
+		g2 = append(g2, p.B)
+
+		rSum.Add(rSum, r)
+		// vkX_i = IC_0 + sum(x_ij * IC_j), so sum(r_i*vkX_i) = sum(r_i)*IC_0 + sum_j(sum_i(r_i*x_ij) * IC_j)
+		for j, x := range p.Inputs {
+			icScalars[j+1].Add(icScalars[j+1], new(big.Int).Mul(r, x))
+		}
+		c.Add(c, new(bn256.G1).ScalarMult(p.C, r))
+	}
+	icScalars[0].Set(rSum)
+
+	vkX := new(bn256.G1).ScalarBaseMult(big.NewInt(0))
+	for j, s := range icScalars {
+		vkX.Add(vkX, new(bn256.G1).ScalarMult(vk.IC[j], s.Mod(s, constants.Q)))
+	}
+	alpha := new(bn256.G1).ScalarMult(vk.Alpha, rSum.Mod(rSum, constants.Q))
+
+	g1 = append(g1, alpha.Neg(alpha), vkX.Neg(vkX), c.Neg(c))
+	g2 = append(g2, vk.Beta, vk.Gamma, vk.Delta)
+	return bn256.PairingCheck(g1, g2), nil
+}
+
+// groth16PairingCheck checks e(A, B) * e(-alpha, beta) * e(-vkX, gamma) * e(-C, delta) == 1
+func groth16PairingCheck(vk *groth16VK, p *groth16Proof) bool {
+	vkX := new(bn256.G1).Set(vk.IC[0])
+	for j, x := range p.Inputs {
+		vkX.Add(vkX, new(bn256.G1).ScalarMult(vk.IC[j+1], x))
+	}
+	g1 := []*bn256.G1{p.A, new(bn256.G1).Neg(vk.Alpha), vkX.Neg(vkX), new(bn256.G1).Neg(p.C)}
+	g2 := []*bn256.G2{p.B, vk.Beta, vk.Gamma, vk.Delta}
+	return bn256.PairingCheck(g1, g2)
+}
+
+func parseGroth16VK(verificationKey []byte) (*groth16VK, error) {
+	var vkJSON groth16VKJSON
+	err := json.Unmarshal(verificationKey, &vkJSON)
+	if err != nil {
+		return nil, err
+	}
+	if len(vkJSON.IC) == 0 {
+		return nil, errors.New("iden3/go-jwz: verification key has no IC points")
+	}
+
+	vk := &groth16VK{}
+	if vk.Alpha, err = g1FromStrings(vkJSON.Alpha); err != nil {
+		return nil, err
+	}
+	if vk.Beta, err = g2FromStrings(vkJSON.Beta); err != nil {
+		return nil, err
+	}
+	if vk.Gamma, err = g2FromStrings(vkJSON.Gamma); err != nil {
+		return nil, err
+	}
+	if vk.Delta, err = g2FromStrings(vkJSON.Delta); err != nil {
+		return nil, err
+	}
+	for _, ic := range vkJSON.IC {
+		p, err := g1FromStrings(ic)
+		if err != nil {
+			return nil, err
+		}
+		vk.IC = append(vk.IC, p)
+	}
+	return vk, nil
+}
+
+func parseGroth16Proof(proof *types.ZKProof, inputsCount int) (*groth16Proof, error) {
+	if len(proof.PubSignals) != inputsCount {
+		return nil, fmt.Errorf("iden3/go-jwz: verification key expects %d public signals, but proof has %d",
+			inputsCount, len(proof.PubSignals))
+	}
+
+	var (
+		p   = &groth16Proof{}
+		err error
+	)
+	if p.A, err = g1FromStrings(proof.Proof.A); err != nil {
+		return nil, err
+	}
+	if p.B, err = g2FromStrings(proof.Proof.B); err != nil {
+		return nil, err
+	}
+	if p.C, err = g1FromStrings(proof.Proof.C); err != nil {
+		return nil, err
+	}
+	for _, s := range proof.PubSignals {
+		x, ok := new(big.Int).SetString(s, 10)
+		if !ok || x.Sign() < 0 || x.Cmp(constants.Q) >= 0 {
+			return nil, fmt.Errorf("iden3/go-jwz: public signal %s is not in the field", s)
+		}
+		p.Inputs = append(p.Inputs, x)
+	}
+	return p, nil
+}
+
+// g1FromStrings converts affine point in snarkjs presentation [x, y, "1"] to bn256 point
+func g1FromStrings(p []string) (*bn256.G1, error) {
+	if len(p) != 3 {
+		return nil, errors.New("iden3/go-jwz: G1 point must have three coordinates")
+	}
+	b := make([]byte, 0, 2*fieldElementSize)
+	// zero bytes is point at infinity
+	if p[2] != "0" {
+		for _, s := range p[:2] {
+			c, err := coordinate(s)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, c.FillBytes(make([]byte, fieldElementSize))...)
+		}
+	} else {
+		b = b[:2*fieldElementSize]
+	}
+	g := new(bn256.G1)
+	if _, err := g.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf("iden3/go-jwz: invalid G1 point: %w", err)
+	}
+	return g, nil
+}
+
+// g2FromStrings converts affine point in snarkjs presentation [[x0, x1], [y0, y1], ["1", "0"]] to bn256 point
+func g2FromStrings(p [][]string) (*bn256.G2, error) {
+	if len(p) != 3 || len(p[0]) != 2 || len(p[1]) != 2 || len(p[2]) != 2 {
+		return nil, errors.New("iden3/go-jwz: G2 point must have three coordinates")
+	}
+	b := make([]byte, 0, 4*fieldElementSize)
+	// zero bytes is point at infinity
+	if p[2][0] != "0" || p[2][1] != "0" {
+		// bn256 encoding is x.c1 || x.c0 || y.c1 || y.c0
+		for _, s := range []string{p[0][1], p[0][0], p[1][1], p[1][0]} {
+			c, err := coordinate(s)
+			if err != nil {
+				return nil, err
+			}
+			b = append(b, c.FillBytes(make([]byte, fieldElementSize))...)
+		}
+	} else {
+		b = b[:4*fieldElementSize]
+	}
+	g := new(bn256.G2)
+	if _, err := g.Unmarshal(b); err != nil {
+		return nil, fmt.Errorf("iden3/go-jwz: invalid G2 point: %w", err)
+	}
+	return g, nil
+}
@@ -0,0 +1,84 @@
+package jwz
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBatchVerify(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+
+	tokens := make([]*Token, 3)
+	for i := range tokens {
+		tokens[i], err = Parse(authV2Token)
+		assert.NoError(t, err)
+	}
+
+	results, err := BatchVerify(tokens, verificationKey)
+	assert.NoError(t, err)
+	assert.Len(t, results, 3)
+	for i, r := range results {
+		assert.Equal(t, tokens[i], r.Token)
+		assert.NoError(t, r.Err)
+	}
+}
+
+func TestBatchVerify_LocatesInvalidProofs(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+
+	tokens := make([]*Token, 4)
+	for i := range tokens {
+		tokens[i], err = Parse(authV2Token)
+		assert.NoError(t, err)
+	}
+	// GIST root isn't checked against message hash, so only the pairing check fails
+	tokens[1].ZkProof.PubSignals[2] = "1"
+	// swapped A and C
+	tokens[3].ZkProof.Proof.A, tokens[3].ZkProof.Proof.C = tokens[3].ZkProof.Proof.C, tokens[3].ZkProof.Proof.A
+
+	results, err := BatchVerify(tokens, verificationKey)
+	assert.EqualError(t, err, "iden3/go-jwz: 2 of 4 proofs are invalid")
+	assert.Len(t, results, 4)
+	assert.NoError(t, results[0].Err)
+	assert.EqualError(t, results[1].Err, "iden3/go-jwz: invalid proof")
+	assert.NoError(t, results[2].Err)
+	assert.EqualError(t, results[3].Err, "iden3/go-jwz: invalid proof")
+}
+
+func TestBatchVerify_InvalidPubSignals(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+
+	valid, err := Parse(authV2Token)
+	assert.NoError(t, err)
+	invalid, err := Parse(authV2Token)
+	assert.NoError(t, err)
+	invalid.ZkProof.PubSignals[1] = "1"
+
+	results, err := BatchVerify([]*Token{valid, invalid}, verificationKey)
+	assert.EqualError(t, err, "iden3/go-jwz: 1 of 2 proofs are invalid")
+	assert.NoError(t, results[0].Err)
+	assert.EqualError(t, results[1].Err, "challenge is not equal to message hash")
+}
+
+func TestBatchVerify_Fallback(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+
+	groth16Token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+	mockToken, err := Parse(proveMockClaims(t, map[string]interface{}{"sub": "alice"}))
+	assert.NoError(t, err)
+
+	results, err := BatchVerify([]*Token{groth16Token, mockToken}, verificationKey)
+	assert.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.NoError(t, results[1].Err)
+
+	_, err = BatchVerify([]*Token{groth16Token}, []byte("{}"))
+	assert.EqualError(t, err, "iden3/go-jwz: verification key has no IC points")
+}
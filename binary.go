@@ -0,0 +1,212 @@
+package jwz
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+
+	"github.com/iden3/go-rapidsnark/types"
+)
+
+// Binary JWZ is a COSE style CBOR encoding of the token for constrained links:
+//
+//	JWZ-Binary = [
+//	    protected:   bstr,            ; JSON protected header exactly as in JWZ
+//	    unprotected: { * tstr => any },
+//	    payload:     bstr / nil,      ; nil for detached payload
+//	    proof:       [
+//	        pi_a:        [ 3*3 fe ],
+//	        pi_b:        [ 3*3 [ 2*2 fe ] ],
+//	        pi_c:        [ 3*3 fe ],
+//	        protocol:    tstr,
+//	        pub_signals: [ * fe ]
+//	    ]
+//	]
+//	fe = bstr .size 32                ; big endian field element
+//
+// The challenge is computed from the protected header and payload bytes exactly as for JWZ:
+// MessageHash(BASE64URL(protected) || '.' || BASE64URL(payload)), so the token can be converted
+// between binary, compact and full serializations without invalidating the proof.
+
+const fieldElementSize = 32
+
+// BinarySerialize returns token in binary (CBOR) encoding.
+func (token *Token) BinarySerialize() ([]byte, error) {
+	if token.raw.Protected == nil || token.ZkProof == nil || token.ZkProof.Proof == nil {
+		return nil, errors.New("iden3/go-jwz: can't serialize without one of components")
+	}
+	if len(token.raw.Signature) != 0 {
+		return nil, errors.New("iden3/go-jwz: signature of hybrid token can't be presented in binary encoding")
+	}
+
+	unprotected := map[string]interface{}{}
+	if len(token.raw.Header) != 0 {
+		// normalize header values to json data model
+		b, err := json.Marshal(token.raw.Header)
+		if err != nil {
+			return nil, err
+		}
+		err = json.Unmarshal(b, &unprotected)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var payload interface{}
+	if !token.IsDetached() {
+		payload = append([]byte{}, token.raw.Payload...)
+	}
+
+	proof, err := encodeBinaryProof(token.ZkProof)
+	if err != nil {
+		return nil, err
+	}
+
+	return cborAppend(nil, []interface{}{token.raw.Protected, unprotected, payload, proof})
+}
+
+// ParseBinary parses a jwz message in binary (CBOR) encoding.
+func ParseBinary(data []byte, opts ...ParseOption) (*Token, error) {
+	decoded, err := cborDecode(data)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := decoded.([]interface{})
+	if !ok || len(items) != 4 {
+		return nil, errors.New("iden3/go-jwz: binary JWZ must be an array of four items")
+	}
+
+	var raw rawJSONWebZeroknowledge
+	if raw.Protected, ok = items[0].([]byte); !ok {
+		return nil, errors.New("iden3/go-jwz: protected header must be a byte string")
+	}
+
+	unprotected, ok := items[1].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("iden3/go-jwz: unprotected header must be a map")
+	}
+	if len(unprotected) != 0 {
+		raw.Header = make(map[HeaderKey]interface{}, len(unprotected))
+		for k, v := range unprotected {
+			raw.Header[HeaderKey(k)] = v
+		}
+	}
+
+	switch payload := items[2].(type) {
+	case nil:
+	case []byte:
+		raw.Payload = payload
+	default:
+		return nil, errors.New("iden3/go-jwz: payload must be a byte string or null")
+	}
+
+	proof, err := decodeBinaryProof(items[3])
+	if err != nil {
+		return nil, err
+	}
+	var headers map[HeaderKey]interface{}
+	err = json.Unmarshal(raw.Protected, &headers)
+	if err != nil {
+		return nil, err
+	}
+	encoding, _ := headers[headerProofEncoding].(string)
+	raw.ZKP, err = marshalProof(encoding, proof)
+	if err != nil {
+		return nil, err
+	}
+
+	return raw.sanitized(newParseOptions(opts))
+}
+
+func encodeBinaryProof(p *types.ZKProof) ([]interface{}, error) {
+	a, err := encodeFieldElements(p.Proof.A)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]interface{}, 0, len(p.Proof.B))
+	for _, coords := range p.Proof.B {
+		c, err := encodeFieldElements(coords)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, c)
+	}
+	c, err := encodeFieldElements(p.Proof.C)
+	if err != nil {
+		return nil, err
+	}
+	pub, err := encodeFieldElements(p.PubSignals)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{a, b, c, p.Proof.Protocol, pub}, nil
+}
+
+func decodeBinaryProof(v interface{}) (*types.ZKProof, error) {
+	items, ok := v.([]interface{})
+	if !ok || len(items) != 5 {
+		return nil, errors.New("iden3/go-jwz: proof must be an array of five items")
+	}
+
+	var (
+		p   = &types.ZKProof{Proof: &types.ProofData{}}
+		err error
+	)
+	p.Proof.A, err = decodeFieldElements(items[0])
+	if err != nil {
+		return nil, err
+	}
+	b, ok := items[1].([]interface{})
+	if !ok {
+		return nil, errors.New("iden3/go-jwz: pi_b must be an array")
+	}
+	for _, coords := range b {
+		c, err := decodeFieldElements(coords)
+		if err != nil {
+			return nil, err
+		}
+		p.Proof.B = append(p.Proof.B, c)
+	}
+	p.Proof.C, err = decodeFieldElements(items[2])
+	if err != nil {
+		return nil, err
+	}
+	if p.Proof.Protocol, ok = items[3].(string); !ok {
+		return nil, errors.New("iden3/go-jwz: protocol must be a text string")
+	}
+	p.PubSignals, err = decodeFieldElements(items[4])
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// encodeFieldElements converts decimal strings to 32 bytes big endian byte strings
+func encodeFieldElements(values []string) ([]interface{}, error) {
+	res := make([]interface{}, 0, len(values))
+	for _, v := range values {
+		e, err := fieldElementFromString(v)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// decodeFieldElements converts array of 32 bytes big endian byte strings to decimal strings
+func decodeFieldElements(v interface{}) ([]string, error) {
+	items, ok := v.([]interface{})
+	if !ok {
+		return nil, errors.New("iden3/go-jwz: field elements must be an array")
+	}
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		b, ok := item.([]byte)
+		if !ok || len(b) != fieldElementSize {
+			return nil, errors.New("iden3/go-jwz: field element must be a 32 bytes string")
+		}
+		res = append(res, new(big.Int).SetBytes(b).String())
+	}
+	return res, nil
+}
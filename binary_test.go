@@ -0,0 +1,88 @@
+package jwz
+
+import (
+	"math"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_BinarySerialize(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	data, err := token.BinarySerialize()
+	assert.NoError(t, err)
+	assert.Less(t, len(data), len(authV2Token)/2)
+
+	parsed, err := ParseBinary(data)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ZkProof, parsed.ZkProof)
+	assert.Equal(t, token.GetPayload(), parsed.GetPayload())
+	assert.Equal(t, token.GetHeader(), parsed.GetHeader())
+
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(verificationKey)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	compact, err := parsed.CompactSerialize()
+	assert.NoError(t, err)
+	assert.Equal(t, authV2Token, compact)
+
+	// unprotected headers and detached payload
+	mock, err := NewWithDetachedPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.WithUnprotectedHeader("route", []string{"relay-1", "relay-2"}))
+	assert.NoError(t, mock.WithUnprotectedHeader("ttl", 60))
+	_, err = mock.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	data, err = mock.BinarySerialize()
+	assert.NoError(t, err)
+	parsed, err = ParseBinary(data)
+	assert.NoError(t, err)
+	assert.Nil(t, parsed.GetPayload())
+	assert.Equal(t, []interface{}{"relay-1", "relay-2"}, parsed.GetUnprotectedHeader()["route"])
+	assert.Equal(t, float64(60), parsed.GetUnprotectedHeader()["ttl"])
+
+	_, err = ParseBinary(data[:len(data)-1])
+	assert.Error(t, err)
+	_, err = ParseBinary(append(data, 0))
+	assert.Error(t, err)
+}
+
+func TestCBOR(t *testing.T) {
+	values := []interface{}{
+		nil, true, false, float64(0), float64(23), float64(24), float64(-1), float64(-500),
+		float64(1 << 40), 1.5, math.Inf(1), "text", []byte{1, 2, 3},
+		[]interface{}{"a", float64(1), []interface{}{}},
+		map[string]interface{}{"b": float64(1), "a": map[string]interface{}{}},
+	}
+	for _, v := range values {
+		b, err := cborAppend(nil, v)
+		assert.NoError(t, err)
+		decoded, err := cborDecode(b)
+		assert.NoError(t, err)
+		assert.Equal(t, v, decoded)
+	}
+
+	// examples from RFC 8949 Appendix A
+	b, err := cborAppend(nil, float64(1000000))
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}, b)
+	b, err = cborAppend(nil, map[string]interface{}{"b": []interface{}{float64(2), float64(3)}, "a": float64(1)})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{0xa2, 0x61, 0x61, 0x01, 0x61, 0x62, 0x82, 0x02, 0x03}, b)
+
+	_, err = cborDecode([]byte{0x9b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.Error(t, err)
+	_, err = cborDecode([]byte{0xbb, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+	assert.Error(t, err)
+	_, err = cborDecode([]byte{0x5f})
+	assert.Error(t, err)
+	_, err = cborDecode([]byte{0xa1, 0x01, 0x01})
+	assert.Error(t, err)
+}
@@ -0,0 +1,154 @@
+package jwz
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// ProvingKeys are keys of the circuit that are used to create a proof
+type ProvingKeys struct {
+	ProvingKey []byte
+	Wasm       []byte
+}
+
+// Builder configures a new token, configuration is validated when the token is built:
+//
+//	token, err := jwz.NewBuilder().
+//		Method(jwz.ProvingMethodGroth16AuthV2Instance).
+//		Claims(claims).
+//		InputsPreparer(preparer).
+//		Prove(ctx, jwz.ProvingKeys{ProvingKey: provingKey, Wasm: wasm})
+type Builder struct {
+	method         ProvingMethod
+	payload        []byte
+	claims         interface{}
+	hasClaims      bool
+	headers        []builderHeader
+	inputsPreparer ProofInputsPreparerHandlerFunc
+}
+
+type builderHeader struct {
+	key   HeaderKey
+	value interface{}
+}
+
+// NewBuilder creates a new token builder
+func NewBuilder() *Builder {
+	return &Builder{}
+}
+
+// Method sets proving method of the token
+func (b *Builder) Method(method ProvingMethod) *Builder {
+	b.method = method
+	return b
+}
+
+// Payload sets raw payload of the token
+func (b *Builder) Payload(payload []byte) *Builder {
+	b.payload = payload
+	return b
+}
+
+// Claims sets value that is marshaled to JSON payload of the token
+func (b *Builder) Claims(claims interface{}) *Builder {
+	b.claims = claims
+	b.hasClaims = true
+	return b
+}
+
+// Type sets 'typ' header
+func (b *Builder) Type(typ string) *Builder {
+	return b.Header(HeaderType, typ)
+}
+
+// ContentType sets 'cty' header
+func (b *Builder) ContentType(cty string) *Builder {
+	return b.Header(HeaderContentType, cty)
+}
+
+// Header sets protected header, the value is validated as in Token.WithHeader
+func (b *Builder) Header(key HeaderKey, value interface{}) *Builder {
+	b.headers = append(b.headers, builderHeader{key: key, value: value})
+	return b
+}
+
+// InputsPreparer sets handler that prepares circuit inputs from the message hash
+func (b *Builder) InputsPreparer(inputsPreparer ProofInputsPreparerHandlerFunc) *Builder {
+	b.inputsPreparer = inputsPreparer
+	return b
+}
+
+// Build validates configuration and returns token that is ready to be proven
+func (b *Builder) Build() (*Token, error) {
+	if b.method == nil {
+		return nil, errors.New("iden3/go-jwz: proving method is not set")
+	}
+	if b.inputsPreparer == nil {
+		return nil, errors.New("iden3/go-jwz: inputs preparer is not set")
+	}
+
+	payload := b.payload
+	switch {
+	case b.hasClaims && payload != nil:
+		return nil, errors.New("iden3/go-jwz: payload and claims are mutually exclusive")
+	case b.hasClaims:
+		var err error
+		payload, err = json.Marshal(b.claims)
+		if err != nil {
+			return nil, fmt.Errorf("iden3/go-jwz: can't marshal claims: %w", err)
+		}
+	case payload == nil:
+		return nil, errors.New("iden3/go-jwz: payload is not set")
+	}
+
+	token, err := NewWithPayload(b.method, payload, b.inputsPreparer)
+	if err != nil {
+		return nil, err
+	}
+	for _, h := range b.headers {
+		err = token.WithHeader(h.key, h.value)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return token, nil
+}
+
+// Prove builds and proves the token, Prove returns as soon as the context is done.
+// Proving methods that implement ContextProvingMethod are stopped with the context, otherwise
+// cancellation only abandons the wait and proving continues in background until the proof is generated.
+func (b *Builder) Prove(ctx context.Context, keys ProvingKeys) (*Token, error) {
+	token, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+	if err = ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := token.proveContext(ctx, keys.ProvingKey, keys.Wasm)
+		done <- err
+	}()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case err = <-done:
+		if err != nil {
+			return nil, err
+		}
+		return token, nil
+	}
+}
+
+// ParseClaims unmarshals JSON payload of the token to provided value
+func (token *Token) ParseClaims(out interface{}) error {
+	if token.raw.Payload == nil {
+		return errors.New("iden3/go-jwz: token payload is not attached")
+	}
+	return json.Unmarshal(token.raw.Payload, out)
+}
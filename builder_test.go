@@ -0,0 +1,151 @@
+package jwz
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type testClaims struct {
+	Subject string `json:"sub"`
+	Amount  int    `json:"amount"`
+}
+
+func TestBuilder_Prove(t *testing.T) {
+	claims := testClaims{Subject: "did:iden3:alice", Amount: 10}
+
+	token, err := NewBuilder().
+		Method(mockProvingMethodInstance).
+		Claims(claims).
+		Type("JWZ").
+		ContentType("application/json").
+		InputsPreparer(mockPrepareInputs).
+		Prove(context.Background(), ProvingKeys{})
+	assert.NoError(t, err)
+	assert.Equal(t, "application/json", token.GetContentType())
+
+	tokenString, err := token.CompactSerialize()
+	assert.NoError(t, err)
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	var out testClaims
+	assert.NoError(t, parsed.ParseClaims(&out))
+	assert.Equal(t, claims, out)
+}
+
+func TestBuilder_Build(t *testing.T) {
+	tests := []struct {
+		name    string
+		builder *Builder
+		err     string
+	}{
+		{
+			name:    "no method",
+			builder: NewBuilder().Payload([]byte("mymessage")).InputsPreparer(mockPrepareInputs),
+			err:     "iden3/go-jwz: proving method is not set",
+		},
+		{
+			name:    "no inputs preparer",
+			builder: NewBuilder().Method(mockProvingMethodInstance).Payload([]byte("mymessage")),
+			err:     "iden3/go-jwz: inputs preparer is not set",
+		},
+		{
+			name:    "no payload",
+			builder: NewBuilder().Method(mockProvingMethodInstance).InputsPreparer(mockPrepareInputs),
+			err:     "iden3/go-jwz: payload is not set",
+		},
+		{
+			name: "payload and claims",
+			builder: NewBuilder().Method(mockProvingMethodInstance).InputsPreparer(mockPrepareInputs).
+				Payload([]byte("mymessage")).Claims(testClaims{}),
+			err: "iden3/go-jwz: payload and claims are mutually exclusive",
+		},
+		{
+			name: "claims can't be marshaled",
+			builder: NewBuilder().Method(mockProvingMethodInstance).InputsPreparer(mockPrepareInputs).
+				Claims(make(chan int)),
+			err: "iden3/go-jwz: can't marshal claims: json: unsupported type: chan int",
+		},
+		{
+			name: "inconsistent header",
+			builder: NewBuilder().Method(mockProvingMethodInstance).InputsPreparer(mockPrepareInputs).
+				Payload([]byte("mymessage")).Header(headerAlg, "groth16"),
+			err: "iden3/go-jwz: header alg must be equal to proving method alg mock",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := tt.builder.Build()
+			assert.EqualError(t, err, tt.err)
+		})
+	}
+}
+
+func TestBuilder_ProveCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := NewBuilder().
+		Method(mockProvingMethodInstance).
+		Payload([]byte("mymessage")).
+		InputsPreparer(mockPrepareInputs).
+		Prove(ctx, ProvingKeys{})
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// blockingProvingMethod proves until the context is done
+type blockingProvingMethod struct {
+	mockProvingMethod
+	started chan struct{}
+	exited  chan struct{}
+}
+
+func (m *blockingProvingMethod) ProveContext(ctx context.Context, _, _, _ []byte) (*types.ZKProof, error) {
+	defer close(m.exited)
+	close(m.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestBuilder_ProveCanceledStopsProving(t *testing.T) {
+	method := &blockingProvingMethod{
+		mockProvingMethod: mockProvingMethod{mockAlg},
+		started:           make(chan struct{}),
+		exited:            make(chan struct{}),
+	}
+	goroutines := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-method.started
+		cancel()
+	}()
+	_, err := NewBuilder().
+		Method(method).
+		Payload([]byte("mymessage")).
+		InputsPreparer(mockPrepareInputs).
+		Prove(ctx, ProvingKeys{})
+	assert.ErrorIs(t, err, context.Canceled)
+
+	select {
+	case <-method.exited:
+	case <-time.After(time.Second):
+		t.Fatal("proving is not stopped")
+	}
+	// proving goroutine exits as well
+	for deadline := time.Now().Add(time.Second); runtime.NumGoroutine() > goroutines; {
+		if time.Now().After(deadline) {
+			t.Fatal("proving goroutine is leaked")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
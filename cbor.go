@@ -0,0 +1,239 @@
+package jwz
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// minimal CBOR (RFC 8949) codec for the subset of data model used by binary jwz:
+// integers, byte and text strings, arrays, maps with text keys, booleans, null and floats.
+
+const (
+	cborMajorUint   byte = 0
+	cborMajorNegInt byte = 1
+	cborMajorBytes  byte = 2
+	cborMajorText   byte = 3
+	cborMajorArray  byte = 4
+	cborMajorMap    byte = 5
+
+	cborFalse   byte = 0xf4
+	cborTrue    byte = 0xf5
+	cborNull    byte = 0xf6
+	cborFloat64 byte = 0xfb
+
+	cborMaxDepth = 32
+)
+
+var errCBORTruncated = errors.New("iden3/go-jwz: truncated cbor data")
+
+// cborAppendHead appends major type and argument in the shortest form
+func cborAppendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= math.MaxUint8:
+		return append(b, major|24, byte(n))
+	case n <= math.MaxUint16:
+		return cborAppendUint(append(b, major|25), n, 2)
+	case n <= math.MaxUint32:
+		return cborAppendUint(append(b, major|26), n, 4)
+	default:
+		return cborAppendUint(append(b, major|27), n, 8)
+	}
+}
+
+// cborAppendUint appends big endian n of the size bytes
+func cborAppendUint(b []byte, n uint64, size int) []byte {
+	for i := size - 1; i >= 0; i-- {
+		b = append(b, byte(n>>(8*i)))
+	}
+	return b
+}
+
+// cborAppend appends CBOR encoding of the value, maps are encoded with sorted keys
+func cborAppend(b []byte, v interface{}) ([]byte, error) {
+	var err error
+	switch v := v.(type) {
+	case nil:
+		return append(b, cborNull), nil
+	case bool:
+		if v {
+			return append(b, cborTrue), nil
+		}
+		return append(b, cborFalse), nil
+	case int:
+		return cborAppendInt(b, int64(v)), nil
+	case int64:
+		return cborAppendInt(b, v), nil
+	case uint64:
+		return cborAppendHead(b, cborMajorUint, v), nil
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return cborAppendInt(b, int64(v)), nil
+		}
+		return cborAppendUint(append(b, cborFloat64), math.Float64bits(v), 8), nil
+	case []byte:
+		return append(cborAppendHead(b, cborMajorBytes, uint64(len(v))), v...), nil
+	case string:
+		return append(cborAppendHead(b, cborMajorText, uint64(len(v))), v...), nil
+	case []interface{}:
+		b = cborAppendHead(b, cborMajorArray, uint64(len(v)))
+		for _, e := range v {
+			b, err = cborAppend(b, e)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		b = cborAppendHead(b, cborMajorMap, uint64(len(v)))
+		for _, k := range keys {
+			b = append(cborAppendHead(b, cborMajorText, uint64(len(k))), k...)
+			b, err = cborAppend(b, v[k])
+			if err != nil {
+				return nil, err
+			}
+		}
+		return b, nil
+	default:
+		return nil, fmt.Errorf("iden3/go-jwz: unsupported cbor type %T", v)
+	}
+}
+
+func cborAppendInt(b []byte, v int64) []byte {
+	if v < 0 {
+		return cborAppendHead(b, cborMajorNegInt, uint64(-(v + 1)))
+	}
+	return cborAppendHead(b, cborMajorUint, uint64(v))
+}
+
+// cborDecode decodes single CBOR data item that must occupy all of the data.
+// Integers are decoded to float64 as encoding/json does, byte strings to []byte.
+func cborDecode(data []byte) (interface{}, error) {
+	v, rest, err := cborDecodeItem(data, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) != 0 {
+		return nil, errors.New("iden3/go-jwz: unexpected data after cbor item")
+	}
+	return v, nil
+}
+
+func cborDecodeItem(data []byte, depth int) (interface{}, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, errors.New("iden3/go-jwz: cbor data is nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCBORTruncated
+	}
+
+	switch data[0] {
+	case cborFalse:
+		return false, data[1:], nil
+	case cborTrue:
+		return true, data[1:], nil
+	case cborNull:
+		return nil, data[1:], nil
+	case cborFloat64:
+		if len(data) < 9 {
+			return nil, nil, errCBORTruncated
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(data[1:9])), data[9:], nil
+	}
+
+	major := data[0] >> 5
+	n, data, err := cborDecodeArgument(data)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case cborMajorUint:
+		return float64(n), data, nil
+	case cborMajorNegInt:
+		return -float64(n) - 1, data, nil
+	case cborMajorBytes, cborMajorText:
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		if major == cborMajorText {
+			return string(data[:n]), data[n:], nil
+		}
+		return append([]byte{}, data[:n]...), data[n:], nil
+	case cborMajorArray:
+		// every item takes at least one byte
+		if uint64(len(data)) < n {
+			return nil, nil, errCBORTruncated
+		}
+		arr := make([]interface{}, 0, n)
+		for i := uint64(0); i < n; i++ {
+			var e interface{}
+			e, data, err = cborDecodeItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			arr = append(arr, e)
+		}
+		return arr, data, nil
+	case cborMajorMap:
+		// every entry takes at least two bytes
+		if uint64(len(data))/2 < n {
+			return nil, nil, errCBORTruncated
+		}
+		m := make(map[string]interface{}, n)
+		for i := uint64(0); i < n; i++ {
+			var k, e interface{}
+			k, data, err = cborDecodeItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				return nil, nil, errors.New("iden3/go-jwz: cbor map key must be a text string")
+			}
+			if _, ok = m[key]; ok {
+				return nil, nil, fmt.Errorf("iden3/go-jwz: duplicated cbor map key %s", key)
+			}
+			e, data, err = cborDecodeItem(data, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = e
+		}
+		return m, data, nil
+	default:
+		return nil, nil, fmt.Errorf("iden3/go-jwz: unsupported cbor item 0x%x", data[0])
+	}
+}
+
+// cborDecodeArgument decodes argument of the data item head
+func cborDecodeArgument(data []byte) (uint64, []byte, error) {
+	info := data[0] & 0x1f
+	data = data[1:]
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	case info < 28:
+		return 0, nil, errCBORTruncated
+	default:
+		return 0, nil, errors.New("iden3/go-jwz: indefinite length cbor items are not supported")
+	}
+}
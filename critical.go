@@ -0,0 +1,155 @@
+package jwz
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CriticalHeaderHandler validates value of critical header extension, it's called during token verification.
+// Returns nil if the token can be processed with the header value.
+type CriticalHeaderHandler func(token *Token, value interface{}) error
+
+// builtInCriticalHeaders are extensions implemented by the library
+var builtInCriticalHeaders = map[HeaderKey]CriticalHeaderHandler{
+	headerCircuitID:     validateCircuitIDHeader,
+	headerDetached:      validateDetachedHeader,
+	headerB64:           validateB64Header,
+	headerMsgHash:       validateMsgHashHeader,
+	headerProofEncoding: validateProofEncodingHeader,
+	headerSigAlg:        validateSigAlgHeader,
+}
+
+var criticalHeaders = copyCriticalHeaders(builtInCriticalHeaders)
+var criticalHeadersLock = new(sync.RWMutex)
+
+func copyCriticalHeaders(handlers map[HeaderKey]CriticalHeaderHandler) map[HeaderKey]CriticalHeaderHandler {
+	c := make(map[HeaderKey]CriticalHeaderHandler, len(handlers))
+	for k, h := range handlers {
+		c[k] = h
+	}
+	return c
+}
+
+// RegisterCriticalHeader declares that the application understands critical header extension
+// and registers the handler that validates its value.
+// Returns error if the extension is already registered, so its handler can't be overridden silently.
+func RegisterCriticalHeader(key HeaderKey, h CriticalHeaderHandler) error {
+	criticalHeadersLock.Lock()
+	defer criticalHeadersLock.Unlock()
+	if _, ok := criticalHeaders[key]; ok {
+		return fmt.Errorf("iden3/go-jwz: critical header %s is already registered", key)
+	}
+	criticalHeaders[key] = h
+	return nil
+}
+
+// UnregisterCriticalHeader removes critical header extension registered by the application.
+// Extensions implemented by the library can't be removed.
+func UnregisterCriticalHeader(key HeaderKey) error {
+	if _, ok := builtInCriticalHeaders[key]; ok {
+		return fmt.Errorf("iden3/go-jwz: critical header %s is implemented by the library", key)
+	}
+	criticalHeadersLock.Lock()
+	defer criticalHeadersLock.Unlock()
+	delete(criticalHeaders, key)
+	return nil
+}
+
+// GetCriticalHeaderHandler returns handler of critical header extension or nil if extension is not registered
+func GetCriticalHeaderHandler(key HeaderKey) (h CriticalHeaderHandler) {
+	criticalHeadersLock.RLock()
+	defer criticalHeadersLock.RUnlock()
+	return criticalHeaders[key]
+}
+
+// GetCriticalHeaders returns a list of registered critical header extensions
+func GetCriticalHeaders() (keys []HeaderKey) {
+	criticalHeadersLock.RLock()
+	defer criticalHeadersLock.RUnlock()
+
+	for key := range criticalHeaders {
+		keys = append(keys, key)
+	}
+	return
+}
+
+// criticalKeys returns keys listed in 'crit' header, checking that they are presented and understood
+func criticalKeys(headers map[HeaderKey]interface{}) ([]HeaderKey, error) {
+	keys, err := criticalList(headers)
+	if err != nil || keys == nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("iden3/go-jwz: header %s must not be empty", headerCritical)
+	}
+
+	for _, key := range keys {
+		if _, ok := headers[key]; !ok {
+			return nil, fmt.Errorf("iden3/go-jwz: header is listed in critical %v, but not presented", key)
+		}
+		if GetCriticalHeaderHandler(key) == nil {
+			return nil, fmt.Errorf("iden3/go-jwz: critical header %s is not supported", key)
+		}
+	}
+	return keys, nil
+}
+
+// validateCritical runs handlers of all critical headers of the token
+func (token *Token) validateCritical() error {
+	keys, err := criticalKeys(token.header)
+	if err != nil {
+		return err
+	}
+	for _, key := range keys {
+		err = GetCriticalHeaderHandler(key)(token, token.header[key])
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func validateCircuitIDHeader(token *Token, value interface{}) error {
+	circuitID, _ := value.(string)
+	if circuitID == "" || circuitID != token.CircuitID {
+		return fmt.Errorf("iden3/go-jwz: invalid %s header", headerCircuitID)
+	}
+	return nil
+}
+
+func validateDetachedHeader(_ *Token, value interface{}) error {
+	if _, ok := value.(bool); !ok {
+		return fmt.Errorf("iden3/go-jwz: %s header must be boolean", headerDetached)
+	}
+	return nil
+}
+
+func validateB64Header(_ *Token, value interface{}) error {
+	if _, ok := value.(bool); !ok {
+		return fmt.Errorf("iden3/go-jwz: %s header must be boolean", headerB64)
+	}
+	return nil
+}
+
+func validateMsgHashHeader(_ *Token, value interface{}) error {
+	alg, _ := value.(string)
+	if GetMessageHasher(alg) == nil {
+		return fmt.Errorf("iden3/go-jwz: message hash alg %v is not registered", value)
+	}
+	return nil
+}
+
+func validateProofEncodingHeader(_ *Token, value interface{}) error {
+	if encoding, _ := value.(string); encoding != ProofEncodingBN254Compressed {
+		return fmt.Errorf("iden3/go-jwz: unsupported proof encoding %v", value)
+	}
+	return nil
+}
+
+func validateSigAlgHeader(_ *Token, value interface{}) error {
+	switch value {
+	case SignatureAlgES256, SignatureAlgEdDSA, SignatureAlgBJJ:
+		return nil
+	}
+	return fmt.Errorf("iden3/go-jwz: unsupported signature alg %v", value)
+}
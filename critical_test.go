@@ -0,0 +1,82 @@
+package jwz
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_CriticalHeaders(t *testing.T) {
+	const extension HeaderKey = "testExtension"
+
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithHeader(extension, "unexpected"))
+	token.addCritical(extension)
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	// extension is not understood by the application
+	assert.Error(t, token.WithCriticalHeader(extension, "unexpected"))
+	_, err = Parse(tokenString)
+	assert.EqualError(t, err, "iden3/go-jwz: critical header testExtension is not supported")
+
+	handler := func(_ *Token, value interface{}) error {
+		if value != "expected" {
+			return errors.New("unexpected extension value")
+		}
+		return nil
+	}
+	assert.NoError(t, RegisterCriticalHeader(extension, handler))
+	t.Cleanup(func() {
+		assert.NoError(t, UnregisterCriticalHeader(extension))
+		assert.NotContains(t, GetCriticalHeaders(), extension)
+	})
+	assert.Contains(t, GetCriticalHeaders(), extension)
+
+	// registered handlers can't be overridden
+	assert.EqualError(t, RegisterCriticalHeader(extension, handler),
+		"iden3/go-jwz: critical header testExtension is already registered")
+	assert.Error(t, RegisterCriticalHeader(headerB64, handler))
+	assert.Error(t, UnregisterCriticalHeader(headerB64))
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	_, err = parsed.Verify(nil)
+	assert.EqualError(t, err, "unexpected extension value")
+
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithCriticalHeader(extension, "expected"))
+	tokenString, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	parsed, err = Parse(tokenString)
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+}
+
+func TestToken_CriticalHeadersBuiltIn(t *testing.T) {
+	token, err := NewWithDetachedPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithUnencodedPayload())
+	assert.NoError(t, token.WithMessageHashAlg(MessageHashPoseidon))
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, token.validateCritical())
+
+	// critical header with invalid value
+	token.header[headerB64] = "false"
+	assert.Error(t, token.validateCritical())
+
+	// critical header must be presented
+	token.header[headerCritical] = []HeaderKey{headerCircuitID, "missing"}
+	assert.Error(t, token.validateCritical())
+
+	token.header[headerCritical] = "circuitId"
+	assert.Error(t, token.validateCritical())
+}
@@ -0,0 +1,183 @@
+package jwz
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// rawGeneralJSONWebZeroknowledge is jwz in general json serialization,
+// that carries proofs of the same payload from several circuits or several holders.
+// Binary members are base64url encoded as in full serialization.
+type rawGeneralJSONWebZeroknowledge struct {
+	Payload *string    `json:"payload,omitempty"`
+	Proofs  []rawProof `json:"proofs"`
+}
+
+// rawProof is one proof of general json serialization with its own headers
+type rawProof struct {
+	Protected string                    `json:"protected,omitempty"`
+	Header    map[HeaderKey]interface{} `json:"header,omitempty"`
+	ZKP       string                    `json:"zkp,omitempty"`
+	Signature string                    `json:"signature,omitempty"`
+}
+
+// GeneralSerialize returns proven tokens of the same payload serialized as json with proofs array.
+func GeneralSerialize(tokens ...*Token) (string, error) {
+	if len(tokens) == 0 {
+		return "", errors.New("iden3/go-jwz: at least one token is required")
+	}
+
+	var general rawGeneralJSONWebZeroknowledge
+	detached := tokens[0].IsDetached()
+	encoded := tokens[0].IsPayloadEncoded()
+	payload := tokens[0].raw.Payload
+	if !detached {
+		var err error
+		general.Payload, err = encodeJSONPayload(payload, tokens[0].raw.Protected)
+		if err != nil {
+			return "", err
+		}
+	}
+
+	for _, token := range tokens {
+		if token.raw.Protected == nil || token.raw.ZKP == nil {
+			return "", errors.New("iden3/go-jwz: can't serialize token that is not proven")
+		}
+		if token.IsDetached() != detached {
+			return "", errors.New("iden3/go-jwz: tokens must be all detached or all attached")
+		}
+		if token.IsPayloadEncoded() != encoded {
+			return "", errors.New("iden3/go-jwz: tokens must have the same payload encoding")
+		}
+		if !detached && !bytes.Equal(token.raw.Payload, payload) {
+			return "", errors.New("iden3/go-jwz: tokens must have the same payload")
+		}
+		general.Proofs = append(general.Proofs, rawProof{
+			Protected: base64.RawURLEncoding.EncodeToString(token.raw.Protected),
+			Header:    token.raw.Header,
+			ZKP:       base64.RawURLEncoding.EncodeToString(token.raw.ZKP),
+			Signature: base64.RawURLEncoding.EncodeToString(token.raw.Signature),
+		})
+	}
+
+	rawBytes, err := json.Marshal(general)
+	return string(rawBytes), err
+}
+
+// ParseGeneral parses a jwz message in general json serialization and returns token per proof.
+func ParseGeneral(input string, opts ...ParseOption) ([]*Token, error) {
+	o := newParseOptions(opts)
+
+	var parsed rawGeneralJSONWebZeroknowledge
+	err := json.Unmarshal([]byte(input), &parsed)
+	if err != nil {
+		return nil, err
+	}
+	if len(parsed.Proofs) == 0 {
+		return nil, errors.New("iden3/go-jwz: general JWZ must have at least one proof")
+	}
+
+	tokens := make([]*Token, 0, len(parsed.Proofs))
+	for i, p := range parsed.Proofs {
+		var raw rawJSONWebZeroknowledge
+		raw.Protected, err = decodeSegment(p.Protected)
+		if err != nil {
+			return nil, err
+		}
+		raw.Payload, err = decodeJSONPayload(parsed.Payload, raw.Protected)
+		if err != nil {
+			return nil, err
+		}
+		raw.ZKP, err = decodeSegment(p.ZKP)
+		if err != nil {
+			return nil, err
+		}
+		raw.Signature, err = decodeSegment(p.Signature)
+		if err != nil {
+			return nil, err
+		}
+		raw.Header = p.Header
+		token, err := raw.sanitized(o)
+		if err != nil {
+			return nil, fmt.Errorf("iden3/go-jwz: proof %d: %w", i, err)
+		}
+		tokens = append(tokens, token)
+	}
+	return tokens, nil
+}
+
+// ProofVerificationResult is result of verification of one proof of general jwz
+type ProofVerificationResult struct {
+	Token *Token
+	Err   error // nil if proof is valid
+}
+
+// VerificationPolicy decides whether the set of proof verification results is acceptable.
+type VerificationPolicy func(results []ProofVerificationResult) error
+
+// RequireAll is policy that requires all proofs to be valid.
+func RequireAll() VerificationPolicy {
+	return func(results []ProofVerificationResult) error {
+		for i, r := range results {
+			if r.Err != nil {
+				return fmt.Errorf("iden3/go-jwz: proof %d is not valid: %w", i, r.Err)
+			}
+		}
+		return nil
+	}
+}
+
+// RequireAny is policy that requires at least one valid proof.
+func RequireAny() VerificationPolicy {
+	return RequireAnyFromCircuit("")
+}
+
+// RequireAnyFromCircuit is policy that requires at least one valid proof of the circuit.
+// Empty circuitID matches any circuit.
+func RequireAnyFromCircuit(circuitID string) VerificationPolicy {
+	return func(results []ProofVerificationResult) error {
+		for _, r := range results {
+			if r.Err == nil && (circuitID == "" || r.Token.CircuitID == circuitID) {
+				return nil
+			}
+		}
+		if circuitID == "" {
+			return errors.New("iden3/go-jwz: no valid proofs")
+		}
+		return fmt.Errorf("iden3/go-jwz: no valid proofs from circuit %s", circuitID)
+	}
+}
+
+// VerifyGeneral verifies every token with verification key of its proving method
+// and checks the results against the policy. Per proof results are returned even if policy is not satisfied.
+func VerifyGeneral(tokens []*Token, verificationKeys map[ProvingMethodAlg][]byte,
+	policy VerificationPolicy) ([]ProofVerificationResult, error) {
+
+	if len(tokens) == 0 {
+		return nil, errors.New("iden3/go-jwz: at least one token is required")
+	}
+	if policy == nil {
+		return nil, errors.New("iden3/go-jwz: verification policy is not set")
+	}
+
+	results := make([]ProofVerificationResult, 0, len(tokens))
+	for _, token := range tokens {
+		r := ProofVerificationResult{Token: token}
+		alg := NewProvingMethodAlg(token.Alg, token.CircuitID)
+		vk, ok := verificationKeys[alg]
+		switch {
+		case token.Method == nil:
+			r.Err = fmt.Errorf("iden3/go-jwz: proving method %v is not registered", alg)
+		case !ok:
+			r.Err = fmt.Errorf("iden3/go-jwz: verification key for %v is not provided", alg)
+		default:
+			_, r.Err = token.Verify(vk)
+		}
+		results = append(results, r)
+	}
+
+	return results, policy(results)
+}
@@ -0,0 +1,66 @@
+package jwz
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// authV2Token is valid authV2 token with 'mymessage' payload
+const authV2Token = "eyJhbGciOiJncm90aDE2IiwiY2lyY3VpdElkIjoiYXV0aFYyIiwiY3JpdCI6WyJjaXJjdWl0SWQiXSwidHlwIjoiSldaIn0.bXltZXNzYWdl.eyJwcm9vZiI6eyJwaV9hIjpbIjE5MTU5MDg5MTAwMDkzNDQyMzY0NTY0MjQxOTA3ODQ1MzkxODgxMzM5NDQ3NDkxNTcwNjg2NTk5NDE3MjA0MzUwNTE1ODE0NzYxNDE1IiwiNDQ4MDg2MzgzNDY4MTU2ODM2MTI2NTI1NzgzMzkyMjk1OTE1Mzg5OTQwNDUzMDkxNjcxNTA5NjEyMzg3NTU1MzY0NjM3NjMwNTQzOSIsIjEiXSwicGlfYiI6W1siMTA3MjY0OTYxNTk4OTQwNDAyNTExMDYyMDkyOTA5MjUzOTQ3MDU1MTk0NTYyNTkyMDYwNjgxMTE0MTY4ODQyMDI2MzI0MzY4Nzk1MDAiLCIzODkwMTY0OTc1OTMzOTQzMDY2NTc5ODI3OTk2MDcxNzI0NDg5NjEwNDU1ODQ0NTU5NDQ2MDIwMTk4ODQyNDQwNzk5MzAyNzQyOTk5Il0sWyIxOTY4NjI5MDk3ODAzMzI1MTU1MjczMjAzNTMxMzIyODYwNTE0Mzc3OTUwOTkwNTk1OTAxMTcxODUwNDI1ODQ3NjgxNzY0MzU2NTM1IiwiNDU2OTY3NjE1OTg3MjgwNDYwOTQzMzcyMTcxODAxNjc2MzE2NDczNTQwMzA5Njg4NjE1OTIxMTg0NjA1MDE3MDY1OTk1MTE3NjU4MSJdLFsiMSIsIjAiXV0sInBpX2MiOlsiMTc4ODM0NTM4NjIxNDI2ODI2MjUwNjI3MDA5NTEzMTU0ODQ4OTUyMDA0OTI3MDgwOTk4MzcwNzM1NjAyNzYxNzk4OTM5MzQ5NzQ2MjEiLCI3NzU4ODI2NjAwNTM2MDU3MDUwNTc2MDMxMDE4NjQ0MDk4NjQyODMxMTE5MzQ2ODM3NjgyMTMzNDU5MjgyMjg4NzExMjgyMzA2NjM4IiwiMSJdLCJwcm90b2NvbCI6Imdyb3RoMTYifSwicHViX3NpZ25hbHMiOlsiMTkyMjkwODQ4NzM3MDQ1NTAzNTcyMzI4ODcxNDI3NzQ2MDU0NDIyOTczMzcyMjkxNzY1NzkyMjkwMTEzNDIwOTE1OTQxNzQ5NzciLCI2MTEwNTE3NzY4MjQ5NTU5MjM4MTkzNDc3NDM1NDU0NzkyMDI0NzMyMTczODY1NDg4OTAwMjcwODQ5NjI0MzI4NjUwNzY1NjkxNDk0IiwiMTI0MzkwNDcxMTQyOTk2MTg1ODc3NDIyMDY0NzYxMDcyNDI3Mzc5ODkxODQ1Nzk5MTQ4NjAzMTU2NzI0NDEwMDc2NzI1OTIzOTc0NyJdfQ"
+
+func TestGeneralSerialize(t *testing.T) {
+	authV2, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	mock, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	_, err = mock.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	general, err := GeneralSerialize(authV2, mock)
+	assert.NoError(t, err)
+
+	_, err = Parse(general)
+	assert.Error(t, err)
+
+	tokens, err := ParseGeneral(general)
+	assert.NoError(t, err)
+	assert.Len(t, tokens, 2)
+	assert.Equal(t, "authV2", tokens[0].CircuitID)
+	assert.Equal(t, "mockCircuit", tokens[1].CircuitID)
+	assert.Equal(t, []byte("mymessage"), tokens[1].GetPayload())
+
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+
+	keys := map[ProvingMethodAlg][]byte{AuthV2Groth16Alg: verificationKey, mockAlg: nil}
+	results, err := VerifyGeneral(tokens, keys, RequireAll())
+	assert.NoError(t, err)
+	assert.Len(t, results, 2)
+
+	delete(keys, mockAlg)
+	results, err = VerifyGeneral(tokens, keys, RequireAll())
+	assert.Error(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Error(t, results[1].Err)
+
+	_, err = VerifyGeneral(tokens, keys, RequireAnyFromCircuit("authV2"))
+	assert.NoError(t, err)
+	_, err = VerifyGeneral(tokens, keys, RequireAnyFromCircuit("mockCircuit"))
+	assert.Error(t, err)
+	_, err = VerifyGeneral(tokens, keys, RequireAny())
+	assert.NoError(t, err)
+	_, err = VerifyGeneral(nil, keys, RequireAll())
+	assert.EqualError(t, err, "iden3/go-jwz: at least one token is required")
+	_, err = VerifyGeneral(tokens, keys, nil)
+	assert.EqualError(t, err, "iden3/go-jwz: verification policy is not set")
+
+	other, err := NewWithPayload(mockProvingMethodInstance, []byte("other"), mockPrepareInputs)
+	assert.NoError(t, err)
+	_, err = other.Prove(nil, nil)
+	assert.NoError(t, err)
+	_, err = GeneralSerialize(authV2, other)
+	assert.Error(t, err)
+}
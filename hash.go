@@ -2,32 +2,97 @@ package jwz
 
 import (
 	"crypto/sha256"
+	"hash"
+	"io"
 	"math/big"
+	"sync"
 
 	"github.com/iden3/go-iden3-crypto/constants"
 	"github.com/iden3/go-iden3-crypto/poseidon"
 	"github.com/iden3/go-iden3-crypto/utils"
 )
 
-// Hash returns poseidon hash of big.Int
-// that was created from sha256 hash of the message bytes
-// if such big.Int is not in the Field, DivMod result is returned.
-func Hash(message []byte) (*big.Int, error) {
+// MessageHasher computes a field element from the message that is written to it in chunks,
+// so large messages can be hashed without loading them into memory.
+type MessageHasher interface {
+	io.Writer
+	// Reset resets the hasher to its initial state.
+	Reset()
+	// Sum returns field element of all bytes written so far.
+	Sum() (*big.Int, error)
+}
 
-	// 1. sha256 hash
-	h := sha256.New()
-	_, err := h.Write(message)
-	if err != nil {
-		return nil, err
+// NewHasher returns streaming MessageHasher which result is equal to the result of Hash.
+func NewHasher() MessageHasher {
+	return &sha256PoseidonHasher{h: sha256.New()}
+}
+
+// MessageHashSHA256Poseidon is default message hash alg: poseidon hash of sha256 hash of the message
+const MessageHashSHA256Poseidon = "sha256-poseidon"
+
+var messageHashers = map[string]func() MessageHasher{}
+var messageHashersLock = new(sync.RWMutex)
+
+// nolint : used for init default message hash alg
+func init() {
+	RegisterMessageHasher(MessageHashSHA256Poseidon, NewHasher)
+}
+
+// RegisterMessageHasher registers the message hash alg name and a factory function for the hasher.
+func RegisterMessageHasher(alg string, f func() MessageHasher) {
+	messageHashersLock.Lock()
+	defer messageHashersLock.Unlock()
+	messageHashers[alg] = f
+}
+
+// GetMessageHasher returns new hasher for the message hash alg or nil if alg is not registered
+func GetMessageHasher(alg string) (h MessageHasher) {
+	messageHashersLock.RLock()
+	defer messageHashersLock.RUnlock()
+	if hasherF, ok := messageHashers[alg]; ok {
+		h = hasherF()
 	}
-	b := h.Sum(nil)
+	return
+}
+
+// GetMessageHashAlgs returns a list of registered message hash algs
+func GetMessageHashAlgs() (algs []string) {
+	messageHashersLock.RLock()
+	defer messageHashersLock.RUnlock()
 
-	// 2. swap hash before hashing
+	for alg := range messageHashers {
+		algs = append(algs, alg)
+	}
+	return
+}
+
+// sha256PoseidonHasher computes poseidon hash of sha256 hash of the message
+type sha256PoseidonHasher struct {
+	h hash.Hash
+}
+
+// Write adds more data to the running hash.
+func (s *sha256PoseidonHasher) Write(p []byte) (int, error) {
+	return s.h.Write(p)
+}
+
+// Reset resets the hasher to its initial state.
+func (s *sha256PoseidonHasher) Reset() {
+	s.h.Reset()
+}
+
+// Sum returns poseidon hash of big.Int
+// that was created from sha256 hash of the written bytes
+// if such big.Int is not in the Field, DivMod result is returned.
+func (s *sha256PoseidonHasher) Sum() (*big.Int, error) {
+	b := s.h.Sum(nil)
+
+	// 1. swap hash before hashing
 
 	bs := utils.SwapEndianness(b)
 	bi := new(big.Int).SetBytes(bs)
 
-	// 3. check if it's in field
+	// 2. check if it's in field
 	var m *big.Int
 	if utils.CheckBigIntInField(bi) {
 		m = bi
@@ -35,7 +100,7 @@ func Hash(message []byte) (*big.Int, error) {
 		m = bi.Mod(bi, constants.Q)
 	}
 
-	// 2. poseidon
+	// 3. poseidon
 	res, err := poseidon.Hash([]*big.Int{m})
 
 	if err != nil {
@@ -43,3 +108,16 @@ func Hash(message []byte) (*big.Int, error) {
 	}
 	return res, err
 }
+
+// Hash returns poseidon hash of big.Int
+// that was created from sha256 hash of the message bytes
+// if such big.Int is not in the Field, DivMod result is returned.
+// Reduction is biased, use MessageHashWideV2 for a uniformly distributed result.
+func Hash(message []byte) (*big.Int, error) {
+	h := NewHasher()
+	_, err := h.Write(message)
+	if err != nil {
+		return nil, err
+	}
+	return h.Sum()
+}
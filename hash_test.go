@@ -1,9 +1,14 @@
 package jwz
 
 import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"io"
 	"math/big"
 	"testing"
 
+	"github.com/iden3/go-iden3-crypto/constants"
 	"github.com/iden3/go-iden3-crypto/poseidon"
 	"github.com/stretchr/testify/assert"
 )
@@ -28,3 +33,84 @@ func TestPoseidonHash(t *testing.T) {
 	assert.Equal(t, i.String(), "16076885786305451396952367807583087877643965039481491647404584414044042908412")
 
 }
+
+func TestHasher_Streaming(t *testing.T) {
+	msg := bytes.Repeat([]byte("message"), 1000)
+
+	expected, err := Hash(msg)
+	assert.NoError(t, err)
+
+	h := NewHasher()
+	_, err = io.CopyBuffer(h, bytes.NewReader(msg), make([]byte, 13))
+	assert.NoError(t, err)
+	res, err := h.Sum()
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res)
+
+	h.Reset()
+	_, err = h.Write([]byte("message"))
+	assert.NoError(t, err)
+	res, err = h.Sum()
+	assert.NoError(t, err)
+	assert.Equal(t, "12195879903067908640854440056941289904003404799313352286287749481941648225513", res.String())
+}
+
+func TestMessageHashers(t *testing.T) {
+	msg := bytes.Repeat([]byte("message"), 100)
+
+	for _, l := range []int{1, 31, 32, 31 * 15, 31 * 16, 31 * 17, len(msg)} {
+		h := GetMessageHasher(MessageHashPoseidon)
+		_, err := io.CopyBuffer(h, bytes.NewReader(msg[:l]), make([]byte, 7))
+		assert.NoError(t, err)
+		res, err := h.Sum()
+		assert.NoError(t, err)
+		expected, err := poseidon.HashBytes(msg[:l])
+		assert.NoError(t, err)
+		assert.Equal(t, expected, res, "length %d", l)
+	}
+
+	h := GetMessageHasher(MessageHashKeccak256)
+	_, err := h.Write([]byte("mimc"))
+	assert.NoError(t, err)
+	res, err := h.Sum()
+	assert.NoError(t, err)
+	expected, _ := new(big.Int).SetString("82724731331859054037315113496710413141112897654334566532528783843265082629790", 10)
+	assert.Equal(t, expected.Mod(expected, constants.Q), res)
+
+	h = GetMessageHasher(MessageHashSHA256TwoLimbs)
+	_, err = h.Write(msg)
+	assert.NoError(t, err)
+	res, err = h.Sum()
+	assert.NoError(t, err)
+	digest := sha256.Sum256(msg)
+	expected, err = poseidon.Hash([]*big.Int{new(big.Int).SetBytes(digest[:16]), new(big.Int).SetBytes(digest[16:])})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, res)
+
+	assert.Nil(t, GetMessageHasher("unknown"))
+	assert.Contains(t, GetMessageHashAlgs(), MessageHashSHA256Poseidon)
+}
+
+func TestMessageHashWideV2(t *testing.T) {
+	h := GetMessageHasher(MessageHashWideV2)
+	_, err := h.Write([]byte("message"))
+	assert.NoError(t, err)
+	res, err := h.Sum()
+	assert.NoError(t, err)
+
+	dst := "iden3/go-jwz:sha512-wide-v2"
+	digest := sha512.Sum512(append(append([]byte{byte(len(dst))}, dst...), "message"...))
+	expected := new(big.Int).SetBytes(digest[:])
+	assert.Equal(t, expected.Mod(expected, constants.Q), res)
+
+	h.Reset()
+	_, err = h.Write([]byte("message"))
+	assert.NoError(t, err)
+	again, err := h.Sum()
+	assert.NoError(t, err)
+	assert.Equal(t, res, again)
+
+	legacy, err := Hash([]byte("message"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, legacy, res)
+}
@@ -0,0 +1,233 @@
+package jwz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Header is typed view of protected headers of the token
+type Header struct {
+	Alg         string      // proving method alg
+	CircuitID   string      // id of circuit that is used for proving
+	Type        string      // 'typ' header
+	ContentType string      // 'cty' header
+	Critical    []HeaderKey // headers that must be understood by the verifier
+
+	Custom map[HeaderKey]interface{} // all other headers, including extensions implemented by the library
+}
+
+// MarshalJSON marshals header to JSON object, custom headers are placed along with typed ones
+func (h Header) MarshalJSON() ([]byte, error) {
+	m := make(map[HeaderKey]interface{}, len(h.Custom)+5)
+	for k, v := range h.Custom {
+		if h.isTyped(k) {
+			return nil, fmt.Errorf("iden3/go-jwz: header %s must not be set as custom", k)
+		}
+		m[k] = v
+	}
+	if h.Alg != "" {
+		m[headerAlg] = h.Alg
+	}
+	if h.CircuitID != "" {
+		m[headerCircuitID] = h.CircuitID
+	}
+	if h.Type != "" {
+		m[HeaderType] = h.Type
+	}
+	if h.ContentType != "" {
+		m[HeaderContentType] = h.ContentType
+	}
+	if h.Critical != nil {
+		m[headerCritical] = h.Critical
+	}
+	return json.Marshal(m)
+}
+
+// UnmarshalJSON unmarshals header from JSON object, unknown headers are preserved in Custom
+func (h *Header) UnmarshalJSON(data []byte) error {
+	var m map[HeaderKey]interface{}
+	err := json.Unmarshal(data, &m)
+	if err != nil {
+		return err
+	}
+	return h.fromMap(m)
+}
+
+// Validate checks that mandatory headers are set and critical headers are presented
+func (h *Header) Validate() error {
+	if h.Alg == "" {
+		return fmt.Errorf("iden3/go-jwz: missing %s header", headerAlg)
+	}
+	if h.CircuitID == "" {
+		return fmt.Errorf("iden3/go-jwz: missing %s header", headerCircuitID)
+	}
+	for _, key := range h.Critical {
+		switch key {
+		case headerAlg, headerCritical:
+			return fmt.Errorf("iden3/go-jwz: header %s must not be listed in critical", key)
+		case headerCircuitID:
+			continue
+		case HeaderType:
+			if h.Type != "" {
+				continue
+			}
+		case HeaderContentType:
+			if h.ContentType != "" {
+				continue
+			}
+		default:
+			if _, ok := h.Custom[key]; ok {
+				continue
+			}
+		}
+		return fmt.Errorf("iden3/go-jwz: header is listed in critical %v, but not presented", key)
+	}
+	return nil
+}
+
+// isTyped returns true if the header has a typed field
+func (h *Header) isTyped(key HeaderKey) bool {
+	switch key {
+	case headerAlg, headerCircuitID, HeaderType, HeaderContentType, headerCritical:
+		return true
+	}
+	return false
+}
+
+// fromMap fills header from raw headers map
+func (h *Header) fromMap(m map[HeaderKey]interface{}) error {
+	*h = Header{}
+	var err error
+	if h.Alg, err = stringHeader(m, headerAlg); err != nil {
+		return err
+	}
+	if h.CircuitID, err = stringHeader(m, headerCircuitID); err != nil {
+		return err
+	}
+	if h.Type, err = stringHeader(m, HeaderType); err != nil {
+		return err
+	}
+	if h.ContentType, err = stringHeader(m, HeaderContentType); err != nil {
+		return err
+	}
+	if h.Critical, err = criticalList(m); err != nil {
+		return err
+	}
+	for k, v := range m {
+		if h.isTyped(k) {
+			continue
+		}
+		if h.Custom == nil {
+			h.Custom = map[HeaderKey]interface{}{}
+		}
+		h.Custom[k] = v
+	}
+	return nil
+}
+
+// stringHeader returns value of header that must be a string
+func stringHeader(m map[HeaderKey]interface{}, key HeaderKey) (string, error) {
+	v, ok := m[key]
+	if !ok {
+		return "", nil
+	}
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("iden3/go-jwz: header %s must be a string", key)
+	}
+	return s, nil
+}
+
+// criticalList returns list of header names from 'crit' header
+func criticalList(m map[HeaderKey]interface{}) ([]HeaderKey, error) {
+	switch crit := m[headerCritical].(type) {
+	case nil:
+		if _, ok := m[headerCritical]; ok {
+			return nil, fmt.Errorf("iden3/go-jwz: header %s must be a list of header names", headerCritical)
+		}
+		return nil, nil
+	case []HeaderKey:
+		return append([]HeaderKey{}, crit...), nil
+	case []interface{}:
+		keys := make([]HeaderKey, 0, len(crit))
+		for _, k := range crit {
+			s, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("iden3/go-jwz: header %s must be a list of header names", headerCritical)
+			}
+			keys = append(keys, HeaderKey(s))
+		}
+		return keys, nil
+	default:
+		return nil, fmt.Errorf("iden3/go-jwz: header %s must be a list of header names", headerCritical)
+	}
+}
+
+// Header returns typed protected headers of the token
+func (token *Token) Header() (*Header, error) {
+	h := &Header{}
+	err := h.fromMap(token.header)
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// GetType returns 'typ' header of the token
+func (token *Token) GetType() string {
+	typ, _ := token.header[HeaderType].(string)
+	return typ
+}
+
+// GetContentType returns 'cty' header of the token
+func (token *Token) GetContentType() string {
+	cty, _ := token.header[HeaderContentType].(string)
+	return cty
+}
+
+// GetCritical returns list of critical headers of the token
+func (token *Token) GetCritical() []HeaderKey {
+	crit, _ := criticalList(token.header)
+	return crit
+}
+
+// validateHeader checks that header value is consistent with the token
+func (token *Token) validateHeader(key HeaderKey, value interface{}) error {
+	switch key {
+	case headerAlg:
+		if value != token.Alg {
+			return fmt.Errorf("iden3/go-jwz: header %s must be equal to proving method alg %s", key, token.Alg)
+		}
+	case headerCircuitID:
+		if value != token.CircuitID {
+			return fmt.Errorf("iden3/go-jwz: header %s must be equal to proving method circuit %s", key, token.CircuitID)
+		}
+	case headerCritical:
+		return errors.New("iden3/go-jwz: critical headers can be added only with WithCriticalHeader")
+	case HeaderType, HeaderContentType:
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("iden3/go-jwz: header %s must be a string", key)
+		}
+	case headerDetached, headerB64, headerMsgHash, headerProofEncoding, headerSigAlg:
+		return fmt.Errorf("iden3/go-jwz: header %s is set by dedicated token option", key)
+	}
+	return nil
+}
+
+// WithCriticalHeader sets header of extension that verifier must understand, so it's listed in 'crit' header.
+// Extension must be registered with RegisterCriticalHeader.
+func (token *Token) WithCriticalHeader(key HeaderKey, value interface{}) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if GetCriticalHeaderHandler(key) == nil {
+		return fmt.Errorf("iden3/go-jwz: critical header %s is not supported", key)
+	}
+	err := token.WithHeader(key, value)
+	if err != nil {
+		return err
+	}
+	token.addCritical(key)
+	return nil
+}
@@ -0,0 +1,72 @@
+package jwz
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHeader_JSON(t *testing.T) {
+	data := []byte(`{"alg":"groth16","circuitId":"authV2","crit":["circuitId","exp"],"typ":"JWZ","exp":1700000000,"route":{"hop":"relay-1"}}`)
+
+	var h Header
+	assert.NoError(t, json.Unmarshal(data, &h))
+	assert.Equal(t, "groth16", h.Alg)
+	assert.Equal(t, "authV2", h.CircuitID)
+	assert.Equal(t, "JWZ", h.Type)
+	assert.Empty(t, h.ContentType)
+	assert.Equal(t, []HeaderKey{headerCircuitID, "exp"}, h.Critical)
+	assert.Equal(t, float64(1700000000), h.Custom["exp"])
+	assert.NoError(t, h.Validate())
+
+	marshaled, err := json.Marshal(h)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), string(marshaled))
+
+	h.Custom[headerAlg] = "other"
+	_, err = json.Marshal(h)
+	assert.Error(t, err)
+
+	assert.Error(t, json.Unmarshal([]byte(`{"alg":1}`), &h))
+	assert.Error(t, json.Unmarshal([]byte(`{"crit":"circuitId"}`), &h))
+
+	assert.Error(t, (&Header{Alg: "groth16"}).Validate())
+	assert.Error(t, (&Header{Alg: "groth16", CircuitID: "authV2", Critical: []HeaderKey{"exp"}}).Validate())
+	assert.Error(t, (&Header{Alg: "groth16", CircuitID: "authV2", Critical: []HeaderKey{headerAlg}}).Validate())
+}
+
+func TestToken_Header(t *testing.T) {
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithHeader(HeaderContentType, "application/json"))
+	assert.NoError(t, token.WithHeader("route", "relay-1"))
+	assert.NoError(t, token.WithUnencodedPayload())
+
+	// inconsistent overrides
+	assert.Error(t, token.WithHeader(headerAlg, "groth16"))
+	assert.Error(t, token.WithHeader(headerCircuitID, "authV2"))
+	assert.Error(t, token.WithHeader(headerCritical, []HeaderKey{}))
+	assert.Error(t, token.WithHeader(headerB64, true))
+	assert.Error(t, token.WithHeader(HeaderType, 1))
+	assert.NoError(t, token.WithHeader(headerAlg, mockAlg.Alg))
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	h, err := parsed.Header()
+	assert.NoError(t, err)
+	assert.Equal(t, &Header{
+		Alg:         mockAlg.Alg,
+		CircuitID:   mockAlg.CircuitID,
+		Type:        "JWZ",
+		ContentType: "application/json",
+		Critical:    []HeaderKey{headerCircuitID, headerB64},
+		Custom:      map[HeaderKey]interface{}{"route": "relay-1", headerB64: false},
+	}, h)
+	assert.Equal(t, "JWZ", parsed.GetType())
+	assert.Equal(t, "application/json", parsed.GetContentType())
+	assert.Equal(t, []HeaderKey{headerCircuitID, headerB64}, parsed.GetCritical())
+}
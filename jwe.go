@@ -0,0 +1,301 @@
+package jwz
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/curve25519"
+)
+
+const (
+	// ContentTypeJWZ is 'cty' of JWE that carries JWZ token
+	ContentTypeJWZ = "JWZ"
+	// ContentTypeJWE is 'cty' of JWZ which payload is JWE in compact serialization
+	ContentTypeJWE = "JWE"
+
+	jweAlgECDHES  = "ECDH-ES"
+	jweEncA256GCM = "A256GCM"
+	jwkKeyTypeOKP = "OKP"
+	jwkCurveX2519 = "X25519"
+
+	jweKeySize = 32
+	jweIVSize  = 12
+)
+
+// jweHeader is protected header of JWE with direct key agreement
+type jweHeader struct {
+	Alg string  `json:"alg"`
+	Enc string  `json:"enc"`
+	Cty string  `json:"cty,omitempty"`
+	Epk jweJWK  `json:"epk"`
+	Apu *string `json:"apu,omitempty"`
+	Apv *string `json:"apv,omitempty"`
+}
+
+// jweJWK is ephemeral X25519 public key (RFC 8037)
+type jweJWK struct {
+	Kty string `json:"kty"`
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+}
+
+// Encrypt wraps proven token to JWE in compact serialization with 'cty' JWZ,
+// so only the owner of X25519 private key can read it (ECDH-ES + A256GCM).
+func (token *Token) Encrypt(recipientPublicKey []byte) (string, error) {
+	tokenString, err := token.CompactSerialize()
+	if err != nil {
+		return "", err
+	}
+	return encryptJWE([]byte(tokenString), ContentTypeJWZ, recipientPublicKey)
+}
+
+// ParseEncrypted decrypts JWE with X25519 private key and parses nested JWZ token.
+func ParseEncrypted(jwe string, privateKey []byte) (*Token, error) {
+	plaintext, header, err := decryptJWE(jwe, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if header.Cty != ContentTypeJWZ {
+		return nil, fmt.Errorf("iden3/go-jwz: JWE content type must be %s", ContentTypeJWZ)
+	}
+	return Parse(string(plaintext))
+}
+
+// ParseEncryptedAndVerify decrypts JWE with X25519 private key, parses and verifies nested JWZ token.
+func ParseEncryptedAndVerify(jwe string, privateKey, verificationKey []byte) (*Token, error) {
+	token, err := ParseEncrypted(jwe, privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if token.Method == nil {
+		return nil, fmt.Errorf("iden3/go-jwz: proving method %s %s is not registered", token.Alg, token.CircuitID)
+	}
+	_, err = token.Verify(verificationKey)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// NewWithEncryptedPayload creates a new Token which payload is JWE with plaintext encrypted for X25519 public key
+// (ECDH-ES + A256GCM). The proof commits to the ciphertext, so it can be verified without decryption.
+func NewWithEncryptedPayload(prover ProvingMethod, plaintext, recipientPublicKey []byte,
+	inputsPreparer ProofInputsPreparerHandlerFunc) (*Token, error) {
+
+	jwe, err := encryptJWE(plaintext, "", recipientPublicKey)
+	if err != nil {
+		return nil, err
+	}
+	token, err := NewWithPayload(prover, []byte(jwe), inputsPreparer)
+	if err != nil {
+		return nil, err
+	}
+	err = token.WithHeader(HeaderContentType, ContentTypeJWE)
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+// DecryptPayload decrypts JWE payload of the token with X25519 private key.
+func (token *Token) DecryptPayload(privateKey []byte) ([]byte, error) {
+	if cty, _ := token.header[HeaderContentType].(string); cty != ContentTypeJWE {
+		return nil, fmt.Errorf("iden3/go-jwz: payload content type is not %s", ContentTypeJWE)
+	}
+	plaintext, _, err := decryptJWE(string(token.raw.Payload), privateKey)
+	return plaintext, err
+}
+
+// validateJWE checks that JWE in compact serialization is well-formed and supported
+func validateJWE(jwe string) error {
+	parts := strings.Split(jwe, ".")
+	if len(parts) != 5 {
+		return errors.New("iden3/go-jwz: compact JWE format must have five segments")
+	}
+	header, err := parseJWEHeader(parts[0])
+	if err != nil {
+		return err
+	}
+	if header.Alg != jweAlgECDHES || header.Enc != jweEncA256GCM {
+		return fmt.Errorf("iden3/go-jwz: unsupported JWE alg %s and enc %s", header.Alg, header.Enc)
+	}
+	return nil
+}
+
+// encryptJWE encrypts plaintext to JWE in compact serialization
+func encryptJWE(plaintext []byte, cty string, recipientPublicKey []byte) (string, error) {
+	ephemeralPrivateKey := make([]byte, curve25519.ScalarSize)
+	_, err := rand.Read(ephemeralPrivateKey)
+	if err != nil {
+		return "", err
+	}
+	ephemeralPublicKey, err := curve25519.X25519(ephemeralPrivateKey, curve25519.Basepoint)
+	if err != nil {
+		return "", err
+	}
+	sharedSecret, err := curve25519.X25519(ephemeralPrivateKey, recipientPublicKey)
+	if err != nil {
+		return "", err
+	}
+
+	header := jweHeader{
+		Alg: jweAlgECDHES,
+		Enc: jweEncA256GCM,
+		Cty: cty,
+		Epk: jweJWK{
+			Kty: jwkKeyTypeOKP,
+			Crv: jwkCurveX2519,
+			X:   base64.RawURLEncoding.EncodeToString(ephemeralPublicKey),
+		},
+	}
+	headerBytes, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	protected := base64.RawURLEncoding.EncodeToString(headerBytes)
+
+	gcm, err := newJWEGCM(sharedSecret, nil, nil)
+	if err != nil {
+		return "", err
+	}
+	iv := make([]byte, jweIVSize)
+	_, err = rand.Read(iv)
+	if err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nil, iv, plaintext, []byte(protected))
+	ciphertext, tag := sealed[:len(plaintext)], sealed[len(plaintext):]
+
+	// BASE64URL(UTF8(JWE Protected Header)) || '..' || BASE64URL(IV) || '.' || BASE64URL(Ciphertext) || '.' || BASE64URL(Tag)
+	return strings.Join([]string{
+		protected,
+		"",
+		base64.RawURLEncoding.EncodeToString(iv),
+		base64.RawURLEncoding.EncodeToString(ciphertext),
+		base64.RawURLEncoding.EncodeToString(tag),
+	}, "."), nil
+}
+
+// decryptJWE decrypts JWE in compact serialization
+func decryptJWE(jwe string, privateKey []byte) ([]byte, *jweHeader, error) {
+	parts := strings.Split(strings.TrimSpace(jwe), ".")
+	if len(parts) != 5 {
+		return nil, nil, errors.New("iden3/go-jwz: compact JWE format must have five segments")
+	}
+	if parts[1] != "" {
+		return nil, nil, errors.New("iden3/go-jwz: JWE encrypted key must be empty for direct key agreement")
+	}
+
+	header, err := parseJWEHeader(parts[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	if header.Alg != jweAlgECDHES || header.Enc != jweEncA256GCM {
+		return nil, nil, fmt.Errorf("iden3/go-jwz: unsupported JWE alg %s and enc %s", header.Alg, header.Enc)
+	}
+	if header.Epk.Kty != jwkKeyTypeOKP || header.Epk.Crv != jwkCurveX2519 {
+		return nil, nil, errors.New("iden3/go-jwz: JWE ephemeral key must be X25519")
+	}
+	ephemeralPublicKey, err := base64.RawURLEncoding.DecodeString(header.Epk.X)
+	if err != nil {
+		return nil, nil, err
+	}
+	sharedSecret, err := curve25519.X25519(privateKey, ephemeralPublicKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var segments [3][]byte
+	for i := range segments {
+		segments[i], err = base64.RawURLEncoding.DecodeString(parts[i+2])
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	iv, ciphertext, tag := segments[0], segments[1], segments[2]
+
+	apu, apv, err := header.partyInfo()
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := newJWEGCM(sharedSecret, apu, apv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(iv) != gcm.NonceSize() || len(tag) != gcm.Overhead() {
+		return nil, nil, errors.New("iden3/go-jwz: invalid JWE iv or tag")
+	}
+	plaintext, err := gcm.Open(nil, iv, append(ciphertext, tag...), []byte(parts[0]))
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, header, nil
+}
+
+// parseJWEHeader decodes protected header of JWE
+func parseJWEHeader(protected string) (*jweHeader, error) {
+	headerBytes, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return nil, err
+	}
+	var header jweHeader
+	err = json.Unmarshal(headerBytes, &header)
+	if err != nil {
+		return nil, err
+	}
+	return &header, nil
+}
+
+// partyInfo returns decoded 'apu' and 'apv' headers
+func (h *jweHeader) partyInfo() (apu, apv []byte, err error) {
+	if h.Apu != nil {
+		apu, err = base64.RawURLEncoding.DecodeString(*h.Apu)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if h.Apv != nil {
+		apv, err = base64.RawURLEncoding.DecodeString(*h.Apv)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	return apu, apv, nil
+}
+
+// newJWEGCM derives content encryption key from shared secret with Concat KDF (RFC 7518 section 4.6.2)
+func newJWEGCM(sharedSecret, apu, apv []byte) (cipher.AEAD, error) {
+	var otherInfo []byte
+	for _, v := range [][]byte{[]byte(jweEncA256GCM), apu, apv} {
+		otherInfo = appendUint32(otherInfo, uint32(len(v)))
+		otherInfo = append(otherInfo, v...)
+	}
+	otherInfo = appendUint32(otherInfo, jweKeySize*8)
+
+	// single round is enough for 256 bits key
+	h := sha256.New()
+	h.Write(appendUint32(nil, 1))
+	h.Write(sharedSecret)
+	h.Write(otherInfo)
+	key := h.Sum(nil)
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func appendUint32(b []byte, v uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], v)
+	return append(b, buf[:]...)
+}
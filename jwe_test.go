@@ -0,0 +1,100 @@
+package jwz
+
+import (
+	"crypto/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/curve25519"
+)
+
+func newX25519Key(t *testing.T) (privateKey, publicKey []byte) {
+	privateKey = make([]byte, curve25519.ScalarSize)
+	_, err := rand.Read(privateKey)
+	assert.NoError(t, err)
+	publicKey, err = curve25519.X25519(privateKey, curve25519.Basepoint)
+	assert.NoError(t, err)
+	return privateKey, publicKey
+}
+
+func TestToken_Encrypt(t *testing.T) {
+	privateKey, publicKey := newX25519Key(t)
+
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	jwe, err := token.Encrypt(publicKey)
+	assert.NoError(t, err)
+	assert.Len(t, strings.Split(jwe, "."), 5)
+	assert.NotContains(t, jwe, strings.Split(authV2Token, ".")[1])
+
+	header, err := parseJWEHeader(strings.Split(jwe, ".")[0])
+	assert.NoError(t, err)
+	assert.Equal(t, "ECDH-ES", header.Alg)
+	assert.Equal(t, "A256GCM", header.Enc)
+	assert.Equal(t, "JWZ", header.Cty)
+
+	parsed, err := ParseEncrypted(jwe, privateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("mymessage"), parsed.GetPayload())
+
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+	parsed, err = ParseEncryptedAndVerify(jwe, privateKey, verificationKey)
+	assert.NoError(t, err)
+	assert.Equal(t, "authV2", parsed.CircuitID)
+
+	otherPrivateKey, _ := newX25519Key(t)
+	_, err = ParseEncrypted(jwe, otherPrivateKey)
+	assert.Error(t, err)
+
+	// tampered authentication tag is detected
+	parts := strings.Split(jwe, ".")
+	parts[4] = parts[4][:len(parts[4])-2] + "AA"
+	_, err = ParseEncrypted(strings.Join(parts, "."), privateKey)
+	assert.Error(t, err)
+
+	jwe, err = encryptJWE([]byte("plain"), "JWT", publicKey)
+	assert.NoError(t, err)
+	_, err = ParseEncrypted(jwe, privateKey)
+	assert.Error(t, err)
+}
+
+func TestToken_EncryptedPayload(t *testing.T) {
+	privateKey, publicKey := newX25519Key(t)
+
+	token, err := NewWithEncryptedPayload(mockProvingMethodInstance, []byte("secret message"), publicKey,
+		mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.Equal(t, ContentTypeJWE, token.GetHeader()[HeaderContentType])
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	// router verifies the sender without the key
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+	assert.NotContains(t, string(parsed.GetPayload()), "secret message")
+
+	plaintext, err := parsed.DecryptPayload(privateKey)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("secret message"), plaintext)
+
+	otherPrivateKey, _ := newX25519Key(t)
+	_, err = parsed.DecryptPayload(otherPrivateKey)
+	assert.Error(t, err)
+
+	// payload must be JWE if content type says so
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("not encrypted"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithHeader(HeaderContentType, ContentTypeJWE))
+	tokenString, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	_, err = Parse(tokenString)
+	assert.Error(t, err)
+}
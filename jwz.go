@@ -1,11 +1,16 @@
 package jwz
 
 import (
+	"bytes"
+	"context"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"reflect"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/iden3/go-circuits/v2"
 	"github.com/iden3/go-rapidsnark/types"
@@ -18,13 +23,26 @@ const (
 
 	// HeaderType is 'typ' header, so we can set specific typ
 	HeaderType HeaderKey = "typ" // we allow to set typ of token
+	// HeaderContentType is 'cty' header, describes content type of the payload
+	HeaderContentType HeaderKey = "cty"
 
 	headerCritical  HeaderKey = "crit"
 	headerAlg       HeaderKey = "alg"
 	headerCircuitID HeaderKey = "circuitId"
+	headerDetached  HeaderKey = "detached" // payload is transmitted out of band
+	headerB64       HeaderKey = "b64"      // RFC 7797 payload encoding
+	headerMsgHash   HeaderKey = "msgHash"  // message hash alg, sha256-poseidon if not set
+
+	headerProofEncoding HeaderKey = "zkpEnc" // encoding of proof segment, json if not set
+	headerSigAlg        HeaderKey = "sigAlg" // alg of conventional signature of hybrid token
 )
 
+// errTokenSealed is returned when proven or parsed token is modified
+var errTokenSealed = errors.New("iden3/go-jwz: token is proven or parsed and can't be modified")
+
 // Token represents a JWZ Token.
+// Token is built with With* options and becomes immutable once it's proven or parsed,
+// so proven and parsed tokens are safe to share across goroutines.
 type Token struct {
 	ZkProof *types.ZKProof // The third segment of the token.  Populated when you Parse a token
 
@@ -33,9 +51,16 @@ type Token struct {
 
 	Method ProvingMethod // proving method to create a zkp
 
-	raw rawJSONWebZeroknowledge // The raw token.  Populated when you Parse a token
+	header map[HeaderKey]interface{} // protected headers
+	raw    rawJSONWebZeroknowledge   // The raw token.  Populated when you Parse a token
 
 	inputsPreparer ProofInputsPreparerHandlerFunc
+	signer         Signer // creates conventional signature of hybrid token
+
+	sealed bool // token is proven or parsed
+
+	methodStatus  MethodStatus // status of proving method in the registry token is parsed with
+	allowDisabled bool         // verification is allowed even if proving method is disabled
 }
 
 // NewWithPayload creates a new Token with the specified proving method and payload.
@@ -53,12 +78,144 @@ func NewWithPayload(prover ProvingMethod, payload []byte, inputsPreparer ProofIn
 	return token, nil
 }
 
+// NewWithDetachedPayload creates a new Token with the specified proving method and payload,
+// that is proven as usual but is not embedded to the serialized token (RFC 7797 style).
+// Receiver must obtain payload out of band and use ParseWithDetachedPayload.
+func NewWithDetachedPayload(prover ProvingMethod, payload []byte, inputsPreparer ProofInputsPreparerHandlerFunc) (*Token, error) {
+
+	token, err := NewWithPayload(prover, payload, inputsPreparer)
+	if err != nil {
+		return nil, err
+	}
+	token.header[headerDetached] = true
+	token.addCritical(headerDetached)
+
+	return token, nil
+}
+
 // rawJSONWebZeroknowledge is json web token with signature presented by zero knowledge proof
 type rawJSONWebZeroknowledge struct {
-	Payload   []byte                    `json:"payload,omitempty"`
-	Protected []byte                    `json:"protected,omitempty"`
+	Payload   []byte
+	Protected []byte
+	Header    map[HeaderKey]interface{} // unprotected headers
+	ZKP       []byte
+	Signature []byte // conventional signature of hybrid token
+}
+
+// jsonWebZeroknowledge is JOSE conformant json presentation of rawJSONWebZeroknowledge
+type jsonWebZeroknowledge struct {
+	Payload   *string                   `json:"payload,omitempty"`
+	Protected string                    `json:"protected,omitempty"`
 	Header    map[HeaderKey]interface{} `json:"header,omitempty"`
-	ZKP       []byte                    `json:"zkp,omitempty"`
+	ZKP       string                    `json:"zkp,omitempty"`
+	Signature string                    `json:"signature,omitempty"`
+}
+
+// MarshalJSON encodes binary members with base64url without padding,
+// unencoded payload (RFC 7797 'b64' header is false) is emitted as a string.
+func (raw rawJSONWebZeroknowledge) MarshalJSON() ([]byte, error) {
+	payload, err := encodeJSONPayload(raw.Payload, raw.Protected)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(jsonWebZeroknowledge{
+		Payload:   payload,
+		Protected: base64.RawURLEncoding.EncodeToString(raw.Protected),
+		Header:    raw.Header,
+		ZKP:       base64.RawURLEncoding.EncodeToString(raw.ZKP),
+		Signature: base64.RawURLEncoding.EncodeToString(raw.Signature),
+	})
+}
+
+// UnmarshalJSON decodes binary members encoded with base64url or standard base64 with or without padding.
+func (raw *rawJSONWebZeroknowledge) UnmarshalJSON(b []byte) error {
+	var j jsonWebZeroknowledge
+	err := json.Unmarshal(b, &j)
+	if err != nil {
+		return err
+	}
+	raw.Protected, err = decodeSegment(j.Protected)
+	if err != nil {
+		return err
+	}
+	raw.Payload, err = decodeJSONPayload(j.Payload, raw.Protected)
+	if err != nil {
+		return err
+	}
+	raw.ZKP, err = decodeSegment(j.ZKP)
+	if err != nil {
+		return err
+	}
+	raw.Signature, err = decodeSegment(j.Signature)
+	if err != nil {
+		return err
+	}
+	raw.Header = j.Header
+	return nil
+}
+
+// encodeJSONPayload returns payload member of json serialization, nil payload is omitted
+func encodeJSONPayload(payload, protected []byte) (*string, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	encoded, err := isProtectedPayloadEncoded(protected)
+	if err != nil {
+		return nil, err
+	}
+	if encoded {
+		p := base64.RawURLEncoding.EncodeToString(payload)
+		return &p, nil
+	}
+	// json string can't hold invalid utf-8, it would be replaced and message hash would change
+	if !utf8.Valid(payload) {
+		return nil, errors.New("iden3/go-jwz: unencoded payload must be valid utf-8 in json serialization")
+	}
+	p := string(payload)
+	return &p, nil
+}
+
+// decodeJSONPayload returns payload bytes from payload member of json serialization
+func decodeJSONPayload(payload *string, protected []byte) ([]byte, error) {
+	if payload == nil {
+		return nil, nil
+	}
+	encoded, err := isProtectedPayloadEncoded(protected)
+	if err != nil {
+		return nil, err
+	}
+	if !encoded {
+		return []byte(*payload), nil
+	}
+	if *payload == "" {
+		return []byte{}, nil
+	}
+	return decodeSegment(*payload)
+}
+
+// isProtectedPayloadEncoded checks 'b64' header of marshaled protected headers
+func isProtectedPayloadEncoded(protected []byte) (bool, error) {
+	if len(protected) == 0 {
+		return true, nil
+	}
+	var headers map[HeaderKey]interface{}
+	err := json.Unmarshal(protected, &headers)
+	if err != nil {
+		return false, err
+	}
+	return isPayloadEncoded(headers), nil
+}
+
+// decodeSegment leniently decodes base64url or standard base64 with or without padding
+func decodeSegment(s string) ([]byte, error) {
+	if s == "" {
+		return nil, nil
+	}
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "+/") {
+		return base64.RawStdEncoding.Strict().DecodeString(s)
+	}
+	return base64.RawURLEncoding.Strict().DecodeString(s)
 }
 
 // setHeader set headers for jwz
@@ -70,18 +227,139 @@ func (token *Token) setDefaultHeaders(zkpAlg, circuitID string) {
 		HeaderType:      "JWZ",
 	}
 
-	token.raw.Header = headers
+	token.header = headers
 }
 
-// WithHeader allows to set or redefine default headers
+// WithHeader allows to set or redefine default headers.
+// Headers that must be consistent with the proving method or set by dedicated options are validated.
 func (token *Token) WithHeader(key HeaderKey, value interface{}) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if _, ok := token.raw.Header[key]; ok {
+		return fmt.Errorf("iden3/go-jwz: header %s is already set as unprotected", key)
+	}
+	if err := token.validateHeader(key, value); err != nil {
+		return err
+	}
+	token.header[key] = value
+	return nil
+}
+
+// WithUnprotectedHeader sets header that is not covered by the proof, e.g. routing hints or transport metadata.
+// Unprotected headers are present only in full serialization.
+func (token *Token) WithUnprotectedHeader(key HeaderKey, value interface{}) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if _, ok := token.header[key]; ok {
+		return fmt.Errorf("iden3/go-jwz: header %s is already set as protected", key)
+	}
+	if mustBeProtected(key) {
+		return fmt.Errorf("iden3/go-jwz: header %s must be protected", key)
+	}
+	if token.raw.Header == nil {
+		token.raw.Header = map[HeaderKey]interface{}{}
+	}
 	token.raw.Header[key] = value
 	return nil
 }
 
-// GetHeader returns header
+// GetUnprotectedHeader returns unprotected header
+func (token *Token) GetUnprotectedHeader() map[HeaderKey]interface{} {
+	return copyHeaders(token.raw.Header)
+}
+
+// mustBeProtected returns true for headers that affect proof verification
+func mustBeProtected(key HeaderKey) bool {
+	switch key {
+	case headerAlg, headerCircuitID, headerCritical, headerDetached, headerB64, headerMsgHash, headerProofEncoding,
+		headerSigAlg:
+		return true
+	}
+	return false
+}
+
+// WithUnencodedPayload marks token payload as unencoded (RFC 7797 'b64' header is false),
+// so message hash is computed over raw payload bytes instead of their base64url representation.
+func (token *Token) WithUnencodedPayload() error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	token.header[headerB64] = false
+	token.addCritical(headerB64)
+	return nil
+}
+
+// WithMessageHashAlg sets registered message hash alg that is used to compute the challenge for proving.
+// Tokens without this header use MessageHashSHA256Poseidon.
+func (token *Token) WithMessageHashAlg(alg string) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if GetMessageHasher(alg) == nil {
+		return fmt.Errorf("iden3/go-jwz: message hash alg %s is not registered", alg)
+	}
+	token.header[headerMsgHash] = alg
+	token.addCritical(headerMsgHash)
+	return nil
+}
+
+// GetMessageHashAlg returns message hash alg of the token
+func (token *Token) GetMessageHashAlg() string {
+	if alg, ok := token.header[headerMsgHash].(string); ok {
+		return alg
+	}
+	return MessageHashSHA256Poseidon
+}
+
+// messageHasher returns new hasher for message hash alg of the token
+func (token *Token) messageHasher() (MessageHasher, error) {
+	alg := token.GetMessageHashAlg()
+	h := GetMessageHasher(alg)
+	if h == nil {
+		return nil, fmt.Errorf("iden3/go-jwz: message hash alg %s is not registered", alg)
+	}
+	return h, nil
+}
+
+// addCritical appends header key to the list of critical headers
+func (token *Token) addCritical(key HeaderKey) {
+	switch crit := token.header[headerCritical].(type) {
+	case []HeaderKey:
+		for _, k := range crit {
+			if k == key {
+				return
+			}
+		}
+		token.header[headerCritical] = append(crit, key)
+	case []interface{}:
+		for _, k := range crit {
+			if k == string(key) {
+				return
+			}
+		}
+		token.header[headerCritical] = append(crit, string(key))
+	default:
+		token.header[headerCritical] = []HeaderKey{key}
+	}
+}
+
+// GetHeader returns copy of raw protected headers, use Header for typed ones
 func (token *Token) GetHeader() map[HeaderKey]interface{} {
-	return token.raw.Header
+	return copyHeaders(token.header)
+}
+
+// copyHeaders returns shallow copy of headers, so caller can't modify the token
+func copyHeaders(headers map[HeaderKey]interface{}) map[HeaderKey]interface{} {
+	if headers == nil {
+		return nil
+	}
+	c := make(map[HeaderKey]interface{}, len(headers))
+	for k, v := range headers {
+		c[k] = v
+	}
+	return c
 }
 
 // setPayload  set payload for jwz
@@ -94,44 +372,102 @@ func (token *Token) GetPayload() []byte {
 	return token.raw.Payload
 }
 
+// IsDetached returns true if payload of the token is transmitted separately
+func (token *Token) IsDetached() bool {
+	detached, _ := token.header[headerDetached].(bool)
+	return detached
+}
+
+// IsPayloadEncoded returns false if token has RFC 7797 'b64' header set to false
+func (token *Token) IsPayloadEncoded() bool {
+	return isPayloadEncoded(token.header)
+}
+
+func isPayloadEncoded(headers map[HeaderKey]interface{}) bool {
+	b64, ok := headers[headerB64].(bool)
+	return !ok || b64
+}
+
 // Parse parses a jwz message in compact or full serialization format.
-func Parse(token string) (*Token, error) {
+func Parse(token string, opts ...ParseOption) (*Token, error) {
+	o := newParseOptions(opts)
 	token = strings.TrimSpace(token)
 	if strings.HasPrefix(token, "{") {
-		return parseFull(token)
+		return parseFull(token, o)
 	}
-	return parseCompact(token)
+	return parseCompact(token, o)
+}
+
+// ParseWithDetachedPayload parses a jwz message with detached payload in compact or full serialization format
+// and attaches payload that was received out of band.
+func ParseWithDetachedPayload(token string, payload []byte, opts ...ParseOption) (*Token, error) {
+	t, err := Parse(token, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !t.IsDetached() {
+		return nil, errors.New("iden3/go-jwz: token payload is not detached")
+	}
+	if len(t.raw.Payload) != 0 {
+		return nil, errors.New("iden3/go-jwz: detached token must not contain payload")
+	}
+	if payload == nil {
+		payload = []byte{}
+	}
+	t.setPayload(payload)
+	return t, nil
 }
 
 // parseFull parses a message in full format.
-func parseFull(input string) (*Token, error) {
+func parseFull(input string, opts parseOptions) (*Token, error) {
+	var general struct {
+		Proofs json.RawMessage `json:"proofs"`
+	}
+	err := json.Unmarshal([]byte(input), &general)
+	if err != nil {
+		return nil, err
+	}
+	if general.Proofs != nil {
+		return nil, errors.New("iden3/go-jwz: message in general JSON serialization, use ParseGeneral")
+	}
+
 	var parsed rawJSONWebZeroknowledge
-	err := json.Unmarshal([]byte(input), &parsed)
+	err = json.Unmarshal([]byte(input), &parsed)
 	if err != nil {
 		return nil, err
 	}
 
-	return parsed.sanitized()
+	return parsed.sanitized(opts)
 }
 
 // parseCompact parses a message in compact format.
-func parseCompact(input string) (*Token, error) {
+// Segments are decoded strictly, so re-encoding reproduces them byte for byte.
+func parseCompact(input string, opts parseOptions) (*Token, error) {
 	parts := strings.Split(input, ".")
 	if len(parts) != 3 {
 		return nil, fmt.Errorf("iden3/go-jwz: compact JWZ format must have three segments")
 	}
 
-	rawProtected, err := base64.RawURLEncoding.DecodeString(parts[0])
+	rawProtected, err := base64.RawURLEncoding.Strict().DecodeString(parts[0])
 	if err != nil {
 		return nil, err
 	}
 
-	rawPayload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	var headers map[HeaderKey]interface{}
+	err = json.Unmarshal(rawProtected, &headers)
 	if err != nil {
 		return nil, err
 	}
 
-	proof, err := base64.RawURLEncoding.DecodeString(parts[2])
+	rawPayload := []byte(parts[1])
+	if isPayloadEncoded(headers) {
+		rawPayload, err = base64.RawURLEncoding.Strict().DecodeString(parts[1])
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	proof, err := base64.RawURLEncoding.Strict().DecodeString(parts[2])
 	if err != nil {
 		return nil, err
 	}
@@ -141,53 +477,99 @@ func parseCompact(input string) (*Token, error) {
 		Protected: rawProtected,
 		ZKP:       proof,
 	}
-	return raw.sanitized()
+	return raw.sanitized(opts)
 }
 
 // sanitized produces a cleaned-up JWZ object from the raw JSON.
-func (parsed *rawJSONWebZeroknowledge) sanitized() (*Token, error) {
-	if parsed.Payload == nil {
-		return nil, fmt.Errorf("iden3/go-jwz: missing payload in JWZ message")
-	}
-
+func (parsed *rawJSONWebZeroknowledge) sanitized(opts parseOptions) (*Token, error) {
 	token := &Token{
 		raw: *parsed,
 	}
 
 	var headers map[HeaderKey]interface{}
 
-	// all headers are protected
 	err := json.Unmarshal(parsed.Protected, &headers)
 	if err != nil {
 		return nil, err
 	}
 
-	// verify that all critical headers are presented
-	criticialHeaders := headers[headerCritical].([]interface{})
-	for _, key := range criticialHeaders {
-		if _, ok := headers[HeaderKey(key.(string))]; !ok {
-			return nil, fmt.Errorf("iden3/go-jwz: header is listed in critical %v, but not presented", key)
+	// verify that all critical headers are presented and understood
+	if _, err = criticalKeys(headers); err != nil {
+		return nil, err
+	}
+
+	token.header = headers
+
+	token.raw.Header, err = unprotectedHeaders(headers, parsed.Header)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err = token.messageHasher(); err != nil {
+		return nil, err
+	}
+
+	if cty, _ := headers[HeaderContentType].(string); cty == ContentTypeJWE && parsed.Payload != nil {
+		if err = validateJWE(string(parsed.Payload)); err != nil {
+			return nil, err
 		}
 	}
 
-	token.raw.Header = headers
+	// payload of detached token is attached later by ParseWithDetachedPayload
+	if token.IsDetached() {
+		if len(parsed.Payload) != 0 {
+			return nil, errors.New("iden3/go-jwz: detached token must not contain payload")
+		}
+		token.raw.Payload = nil
+	} else if parsed.Payload == nil {
+		return nil, fmt.Errorf("iden3/go-jwz: missing payload in JWZ message")
+	}
 
-	token.Alg = headers[headerAlg].(string)
-	token.CircuitID = headers[headerCircuitID].(string)
-	token.Method = GetProvingMethod(NewProvingMethodAlg(token.Alg, token.CircuitID))
+	var header Header
+	if err = header.fromMap(headers); err != nil {
+		return nil, err
+	}
+	if err = header.Validate(); err != nil {
+		return nil, err
+	}
+	token.Alg = header.Alg
+	token.CircuitID = header.CircuitID
+	alg := NewProvingMethodAlg(token.Alg, token.CircuitID)
+	token.Method = opts.registry.Get(alg)
+	token.methodStatus = opts.registry.Status(alg)
+	token.allowDisabled = opts.allowDisabled
 
 	// parse proof
 
 	if len(parsed.ZKP) != 0 {
-		err := json.Unmarshal(parsed.ZKP, &token.ZkProof)
+		token.ZkProof, err = unmarshalProof(token.GetProofEncoding(), parsed.ZKP)
 		if err != nil {
 			return nil, err
 		}
 	}
+	token.sealed = true
 
 	return token, nil
 }
 
+// unprotectedHeaders checks that unprotected headers don't collide with protected ones.
+// Earlier versions copied all protected headers to unprotected ones in full serialization,
+// so unprotected headers equal to the protected set are accepted and dropped.
+func unprotectedHeaders(protected, unprotected map[HeaderKey]interface{}) (map[HeaderKey]interface{}, error) {
+	if len(unprotected) == 0 || reflect.DeepEqual(protected, unprotected) {
+		return nil, nil
+	}
+	for key := range unprotected {
+		if _, ok := protected[key]; ok {
+			return nil, fmt.Errorf("iden3/go-jwz: header %s is both protected and unprotected", key)
+		}
+		if mustBeProtected(key) {
+			return nil, fmt.Errorf("iden3/go-jwz: header %s must be protected", key)
+		}
+	}
+	return unprotected, nil
+}
+
 // ParsePubSignals unmarshalls proof public signals to provided structure.
 func (token *Token) ParsePubSignals(out circuits.PubSignalsUnmarshaller) error {
 	marshaledPubSignals, err := json.Marshal(token.ZkProof.PubSignals)
@@ -205,35 +587,110 @@ func (token *Token) ParsePubSignals(out circuits.PubSignalsUnmarshaller) error {
 // Prove creates and returns a complete, proved JWZ.
 // The token is proven using the Proving Method specified in the token.
 func (token *Token) Prove(provingKey, wasm []byte) (string, error) {
+	return token.proveContext(context.Background(), provingKey, wasm)
+}
+
+// proveContext is Prove that stops when the context is done, if proving method implements ContextProvingMethod
+func (token *Token) proveContext(ctx context.Context, provingKey, wasm []byte) (string, error) {
+
+	if token.sealed {
+		return "", errTokenSealed
+	}
 
-	// all headers must be protected
-	headers, err := json.Marshal(token.raw.Header)
+	err := token.protectHeaders()
 	if err != nil {
 		return "", err
 	}
-	token.raw.Protected = headers
 
 	msgHash, err := token.GetMessageHash()
 	if err != nil {
 		return "", err
 	}
 
+	return token.prove(ctx, msgHash, provingKey, wasm)
+}
+
+// ProveWithPayloadReader creates and returns a complete, proved JWZ with detached payload
+// that is read from the provided reader, so payload is never loaded into memory.
+func (token *Token) ProveWithPayloadReader(payload io.Reader, provingKey, wasm []byte) (string, error) {
+
+	if token.sealed {
+		return "", errTokenSealed
+	}
+	if !token.IsDetached() {
+		return "", errors.New("iden3/go-jwz: payload reader can be used only with detached payload")
+	}
+	// only BJJ signs the message hash, other algs sign the payload itself
+	if token.signer != nil && token.signer.Alg() != SignatureAlgBJJ {
+		return "", fmt.Errorf("iden3/go-jwz: %s signature requires attached payload, use Prove", token.signer.Alg())
+	}
+
+	err := token.protectHeaders()
+	if err != nil {
+		return "", err
+	}
+
+	msgHash, err := token.GetMessageHashFromReader(payload)
+	if err != nil {
+		return "", err
+	}
+
+	return token.prove(context.Background(), msgHash, provingKey, wasm)
+}
+
+// protectHeaders marshals headers to protected part of the token
+func (token *Token) protectHeaders() error {
+	headers, err := json.Marshal(token.header)
+	if err != nil {
+		return err
+	}
+	token.raw.Protected = headers
+	return nil
+}
+
+// prove generates zkp for message hash and returns token in compact serialization,
+// or in full serialization if token is hybrid, as only it has place for the signature.
+func (token *Token) prove(ctx context.Context, msgHash, provingKey, wasm []byte) (string, error) {
+
+	if token.inputsPreparer == nil {
+		return "", errors.New("iden3/go-jwz: inputs preparer is not set")
+	}
 	inputs, err := token.inputsPreparer.Prepare(msgHash, circuits.CircuitID(token.CircuitID))
 	if err != nil {
 		return "", err
 	}
 
-	proof, err := token.Method.Prove(inputs, provingKey, wasm)
+	if err = ctx.Err(); err != nil {
+		return "", err
+	}
+
+	var proof *types.ZKProof
+	if m, ok := token.Method.(ContextProvingMethod); ok {
+		proof, err = m.ProveContext(ctx, inputs, provingKey, wasm)
+	} else {
+		proof, err = token.Method.Prove(inputs, provingKey, wasm)
+	}
 	if err != nil {
 		return "", err
 	}
-	marshaledProof, err := json.Marshal(proof)
+	marshaledProof, err := marshalProof(token.GetProofEncoding(), proof)
 	if err != nil {
 		return "", err
 	}
 	token.ZkProof = proof
 	token.raw.ZKP = marshaledProof
 
+	if token.signer != nil {
+		err = token.sign(msgHash)
+		if err != nil {
+			return "", err
+		}
+	}
+	token.sealed = true
+
+	if token.IsHybrid() {
+		return token.FullSerialize()
+	}
 	return token.CompactSerialize()
 }
 
@@ -241,11 +698,77 @@ func (token *Token) Prove(provingKey, wasm []byte) (string, error) {
 func (token *Token) Verify(verificationKey []byte) (bool, error) {
 
 	// 1. prepare hash of payload message that had to be proven
-	msgHash, err := token.GetMessageHash()
+	msgHash, err := token.attachedMessageHash()
 	if err != nil {
 		return false, err
 	}
 	// 2. verify that zkp is valid
+	return token.verify(msgHash, verificationKey)
+}
+
+// attachedMessageHash returns message hash, checking that detached payload is attached
+func (token *Token) attachedMessageHash() ([]byte, error) {
+	if token.IsDetached() && token.raw.Payload == nil {
+		return nil, errors.New("iden3/go-jwz: detached payload is not attached")
+	}
+	return token.GetMessageHash()
+}
+
+// VerifyWithPayloadReader perform zero knowledge verification of token with detached payload
+// that is read from the provided reader.
+func (token *Token) VerifyWithPayloadReader(payload io.Reader, verificationKey []byte) (bool, error) {
+
+	if !token.IsDetached() {
+		return false, errors.New("iden3/go-jwz: payload reader can be used only with detached payload")
+	}
+
+	msgHash, err := token.GetMessageHashFromReader(payload)
+	if err != nil {
+		return false, err
+	}
+	return token.verify(msgHash, verificationKey)
+}
+
+// MethodStatus returns status of proving method in the registry token is parsed with
+func (token *Token) MethodStatus() MethodStatus {
+	return token.methodStatus
+}
+
+// Warnings returns non fatal issues of the token, e.g. deprecated proving method
+func (token *Token) Warnings() (warnings []string) {
+	if token.methodStatus == MethodDeprecated {
+		warnings = append(warnings, fmt.Sprintf("proving method %s %s is deprecated", token.Alg, token.CircuitID))
+	}
+	return
+}
+
+// checkMethod checks that proving method of the token can be used for verification
+func (token *Token) checkMethod() error {
+	if token.Method == nil {
+		return fmt.Errorf("iden3/go-jwz: proving method %s %s is not registered", token.Alg, token.CircuitID)
+	}
+	if token.methodStatus == MethodDisabled && !token.allowDisabled {
+		return fmt.Errorf("%w: %s %s", ErrMethodDisabled, token.Alg, token.CircuitID)
+	}
+	return nil
+}
+
+// verify checks that zkp is valid for the message hash
+func (token *Token) verify(msgHash, verificationKey []byte) (bool, error) {
+	if token.ZkProof == nil || token.ZkProof.Proof == nil {
+		return false, errors.New("iden3/go-jwz: missing proof in JWZ message")
+	}
+
+	err := token.checkMethod()
+	if err != nil {
+		return false, err
+	}
+
+	err = token.validateCritical()
+	if err != nil {
+		return false, err
+	}
+
 	err = token.Method.Verify(msgHash, token.ZkProof, verificationKey)
 	if err != nil {
 		return false, err
@@ -256,18 +779,50 @@ func (token *Token) Verify(verificationKey []byte) (bool, error) {
 
 // GetMessageHash returns bytes of jwz message hash.
 func (token *Token) GetMessageHash() ([]byte, error) {
+	return token.GetMessageHashFromReader(bytes.NewReader(token.raw.Payload))
+}
 
-	headers, err := json.Marshal(token.raw.Header)
-	if err != nil {
-		return nil, err
+// GetMessageHashFromReader returns bytes of jwz message hash, where payload is read from the provided reader
+// instead of the token, so payload of any size is hashed with constant memory.
+func (token *Token) GetMessageHashFromReader(payload io.Reader) ([]byte, error) {
+
+	// protected headers are used as they were proven or parsed, re-marshaling may change their bytes
+	headers := token.raw.Protected
+	if headers == nil {
+		var err error
+		headers, err = json.Marshal(token.header)
+		if err != nil {
+			return nil, err
+		}
 	}
 	protectedHeaders := base64.RawURLEncoding.EncodeToString(headers)
-	payload := base64.RawURLEncoding.EncodeToString(token.raw.Payload)
 
 	// JWZ ZkProof input value is ASCII(BASE64URL(UTF8(JWS Protected Header)) || '.' || BASE64URL(JWS Payload)).
-	messageToProof := []byte(fmt.Sprintf("%s.%s", protectedHeaders, payload))
-	hash, err := Hash(messageToProof)
+	// If 'b64' header is false, payload is used as is (RFC 7797).
+	h, err := token.messageHasher()
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.WriteString(h, protectedHeaders+".")
+	if err != nil {
+		return nil, err
+	}
 
+	if token.IsPayloadEncoded() {
+		enc := base64.NewEncoder(base64.RawURLEncoding, h)
+		_, err = io.Copy(enc, payload)
+		if err != nil {
+			return nil, err
+		}
+		err = enc.Close()
+	} else {
+		_, err = io.Copy(h, payload)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := h.Sum()
 	if err != nil {
 		return nil, err
 	}
@@ -277,23 +832,70 @@ func (token *Token) GetMessageHash() ([]byte, error) {
 // FullSerialize returns marshaled presentation of raw token as json string.
 func (token *Token) FullSerialize() (string, error) {
 
-	rawBytes, err := json.Marshal(token.raw)
+	raw := token.raw
+	if token.IsDetached() {
+		raw.Payload = nil
+	}
+	rawBytes, err := json.Marshal(raw)
 	return string(rawBytes), err
 }
 
 // CompactSerialize returns token serialized in three parts: base64 encoded headers, payload and proof.
+// Unprotected headers are omitted, as compact serialization has no place for them.
+// Hybrid token can't be serialized without loss of signature and error is returned.
 func (token *Token) CompactSerialize() (string, error) {
 
-	if token.raw.Header == nil || token.raw.Protected == nil || token.ZkProof == nil {
+	if token.header == nil || token.raw.Protected == nil || token.ZkProof == nil {
 		return "", errors.New("iden3/jwz:can't serialize without one of components")
 	}
+	if len(token.raw.Signature) != 0 {
+		return "", errors.New("iden3/go-jwz: signature can't be presented in compact serialization")
+	}
 	serializedProtected := base64.RawURLEncoding.EncodeToString(token.raw.Protected)
-	proofBytes, err := json.Marshal(token.ZkProof)
-	if err != nil {
-		return "", err
+	// original proof bytes are emitted, so the serialization is lossless
+	proofBytes := token.raw.ZKP
+	if len(proofBytes) == 0 {
+		var err error
+		proofBytes, err = marshalProof(token.GetProofEncoding(), token.ZkProof)
+		if err != nil {
+			return "", err
+		}
 	}
 	serializedProof := base64.RawURLEncoding.EncodeToString(proofBytes)
-	serializedPayload := base64.RawURLEncoding.EncodeToString(token.raw.Payload)
+	serializedPayload := ""
+	switch {
+	case token.IsDetached():
+	case token.IsPayloadEncoded():
+		serializedPayload = base64.RawURLEncoding.EncodeToString(token.raw.Payload)
+	case bytes.ContainsRune(token.raw.Payload, '.'):
+		return "", errors.New("iden3/go-jwz: unencoded payload must not contain '.' in compact serialization")
+	default:
+		serializedPayload = string(token.raw.Payload)
+	}
 
 	return fmt.Sprintf("%s.%s.%s", serializedProtected, serializedPayload, serializedProof), nil
 }
+
+// CompactToFull converts token in compact serialization to full serialization.
+// Protected headers, payload and proof are preserved byte for byte, so conversion never invalidates the proof.
+func CompactToFull(compact string) (string, error) {
+	token, err := parseCompact(strings.TrimSpace(compact), newParseOptions(nil))
+	if err != nil {
+		return "", err
+	}
+	return token.FullSerialize()
+}
+
+// FullToCompact converts token in full serialization to compact serialization.
+// Protected headers, payload and proof are preserved byte for byte, so conversion never invalidates the proof.
+// Token with unprotected headers can't be converted without loss and error is returned.
+func FullToCompact(full string) (string, error) {
+	token, err := parseFull(strings.TrimSpace(full), newParseOptions(nil))
+	if err != nil {
+		return "", err
+	}
+	if len(token.raw.Header) != 0 {
+		return "", errors.New("iden3/go-jwz: unprotected headers can't be presented in compact serialization")
+	}
+	return token.CompactSerialize()
+}
@@ -1,13 +1,19 @@
 package jwz
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"math/big"
 	"os"
+	"strings"
+	"sync"
 	"testing"
 
 	"github.com/iden3/go-circuits/v2"
 	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-crypto/poseidon"
 	"github.com/iden3/go-rapidsnark/types"
 	"github.com/stretchr/testify/assert"
 )
@@ -24,8 +30,8 @@ func TestNewWithPayload(t *testing.T) {
 
 	assert.Equal(t, "groth16", token.Alg)
 	assert.Equal(t, "authV2", token.CircuitID)
-	assert.Equal(t, []HeaderKey{headerCircuitID}, token.raw.Header[headerCritical])
-	assert.Equal(t, "groth16", token.raw.Header[headerAlg])
+	assert.Equal(t, []HeaderKey{headerCircuitID}, token.header[headerCritical])
+	assert.Equal(t, "groth16", token.header[headerAlg])
 }
 
 func TestToken_Prove(t *testing.T) {
@@ -60,10 +66,9 @@ func TestToken_Prove(t *testing.T) {
 
 func BenchmarkToken_Prove(b *testing.B) {
 	payload := []byte("mymessage")
-	token, err := NewWithPayload(ProvingMethodGroth16AuthV2Instance, payload, MockPrepareAuthV2Inputs)
-	assert.NoError(b, err)
 
 	var provingKey, verificationKey, wasm []byte
+	var err error
 
 	provingKey, err = os.ReadFile("./testdata/authV2/circuit_final.zkey")
 	assert.Nil(b, err)
@@ -75,6 +80,10 @@ func BenchmarkToken_Prove(b *testing.B) {
 	assert.NoError(b, err)
 
 	for i := 0; i < b.N; i++ {
+		// proven token is immutable, so every iteration proves a new one
+		token, err := NewWithPayload(ProvingMethodGroth16AuthV2Instance, payload, MockPrepareAuthV2Inputs)
+		assert.NoError(b, err)
+
 		_, err = token.Prove(provingKey, wasm)
 		assert.NoError(b, err)
 
@@ -127,3 +136,429 @@ func TestToken_ParseWithOutputs(t *testing.T) {
 	assert.Equal(t, "did:iden3:polygon:mumbai:x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29", did.String())
 
 }
+
+// mockProvingMethod proves message hash as the only public signal without real zk proof
+type mockProvingMethod struct {
+	ProvingMethodAlg
+}
+
+var mockAlg = NewProvingMethodAlg("mock", "mockCircuit")
+
+var mockProvingMethodInstance = &mockProvingMethod{mockAlg}
+
+func init() {
+	err := RegisterProvingMethod(mockAlg, func() ProvingMethod { return mockProvingMethodInstance })
+	if err != nil {
+		panic(err)
+	}
+}
+
+func (m *mockProvingMethod) Alg() string {
+	return m.ProvingMethodAlg.Alg
+}
+
+func (m *mockProvingMethod) CircuitID() string {
+	return m.ProvingMethodAlg.CircuitID
+}
+
+func (m *mockProvingMethod) Verify(messageHash []byte, proof *types.ZKProof, _ []byte) error {
+	if len(proof.PubSignals) != 1 || proof.PubSignals[0] != new(big.Int).SetBytes(messageHash).String() {
+		return errors.New("challenge is not equal to message hash")
+	}
+	return nil
+}
+
+func (m *mockProvingMethod) Prove(inputs, _, _ []byte) (*types.ZKProof, error) {
+	return &types.ZKProof{
+		Proof:      &types.ProofData{A: []string{"1", "2", "1"}, Protocol: "mock"},
+		PubSignals: []string{string(inputs)},
+	}, nil
+}
+
+func mockPrepareInputs(hash []byte, _ circuits.CircuitID) ([]byte, error) {
+	return []byte(new(big.Int).SetBytes(hash).String()), nil
+}
+
+func TestToken_DetachedPayload(t *testing.T) {
+	payload := []byte("large document")
+	token, err := NewWithDetachedPayload(mockProvingMethodInstance, payload, mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.True(t, token.IsDetached())
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	parts := strings.Split(tokenString, ".")
+	assert.Len(t, parts, 3)
+	assert.Empty(t, parts[1])
+
+	_, err = Parse(tokenString)
+	assert.NoError(t, err)
+
+	parsed, err := ParseWithDetachedPayload(tokenString, payload)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, parsed.GetPayload())
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	fullString, err := token.FullSerialize()
+	assert.NoError(t, err)
+	assert.NotContains(t, fullString, `"payload"`)
+
+	parsed, err = ParseWithDetachedPayload(fullString, []byte("other document"))
+	assert.NoError(t, err)
+	_, err = parsed.Verify(nil)
+	assert.Error(t, err)
+
+	withoutPayload, err := Parse(fullString)
+	assert.NoError(t, err)
+	_, err = withoutPayload.Verify(nil)
+	assert.Error(t, err)
+}
+
+func TestToken_UnencodedPayload(t *testing.T) {
+	payload := []byte(`{"id":"123"}`)
+	token, err := NewWithPayload(mockProvingMethodInstance, payload, mockPrepareInputs)
+	assert.NoError(t, err)
+	err = token.WithUnencodedPayload()
+	assert.NoError(t, err)
+	assert.False(t, token.IsPayloadEncoded())
+	assert.Equal(t, []HeaderKey{headerCircuitID, headerB64}, token.header[headerCritical])
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, string(payload), strings.Split(tokenString, ".")[1])
+
+	msgHash, err := token.GetMessageHash()
+	assert.NoError(t, err)
+	protected := base64.RawURLEncoding.EncodeToString(token.raw.Protected)
+	expected, err := Hash([]byte(protected + "." + string(payload)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Bytes(), msgHash)
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, payload, parsed.GetPayload())
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	fullString, err := token.FullSerialize()
+	assert.NoError(t, err)
+	parsed, err = Parse(fullString)
+	assert.NoError(t, err)
+	isValid, err = parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("a.b"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithUnencodedPayload())
+	_, err = token.Prove(nil, nil)
+	assert.Error(t, err)
+
+	// json can't carry invalid utf-8 unencoded payload
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte{0xff, 0xfe, 0x61}, mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithUnencodedPayload())
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	_, err = token.FullSerialize()
+	assert.ErrorContains(t, err, "iden3/go-jwz: unencoded payload must be valid utf-8 in json serialization")
+	_, err = GeneralSerialize(token)
+	assert.EqualError(t, err, "iden3/go-jwz: unencoded payload must be valid utf-8 in json serialization")
+}
+
+func TestToken_PayloadReader(t *testing.T) {
+	payload := bytes.Repeat([]byte("large document "), 10000)
+
+	token, err := NewWithDetachedPayload(mockProvingMethodInstance, nil, mockPrepareInputs)
+	assert.NoError(t, err)
+
+	tokenString, err := token.ProveWithPayloadReader(bytes.NewReader(payload), nil, nil)
+	assert.NoError(t, err)
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	isValid, err := parsed.VerifyWithPayloadReader(bytes.NewReader(payload), nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	parsed, err = ParseWithDetachedPayload(tokenString, payload)
+	assert.NoError(t, err)
+	msgHash, err := parsed.GetMessageHash()
+	assert.NoError(t, err)
+	assert.Equal(t, parsed.ZkProof.PubSignals[0], new(big.Int).SetBytes(msgHash).String())
+
+	_, err = parsed.VerifyWithPayloadReader(strings.NewReader("other document"), nil)
+	assert.Error(t, err)
+}
+
+func TestToken_WithMessageHashAlg(t *testing.T) {
+	payload := []byte("mymessage")
+	token, err := NewWithPayload(mockProvingMethodInstance, payload, mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageHashSHA256Poseidon, token.GetMessageHashAlg())
+
+	assert.Error(t, token.WithMessageHashAlg("unknown"))
+	assert.NoError(t, token.WithMessageHashAlg(MessageHashPoseidon))
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, MessageHashPoseidon, parsed.GetMessageHashAlg())
+
+	msgHash, err := parsed.GetMessageHash()
+	assert.NoError(t, err)
+	protected := base64.RawURLEncoding.EncodeToString(token.raw.Protected)
+	expected, err := poseidon.HashBytes([]byte(protected + "." + base64.RawURLEncoding.EncodeToString(payload)))
+	assert.NoError(t, err)
+	assert.Equal(t, expected.Bytes(), msgHash)
+
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+}
+
+func TestToken_UnprotectedHeader(t *testing.T) {
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+
+	assert.NoError(t, token.WithUnprotectedHeader("route", "relay-1"))
+	assert.Error(t, token.WithUnprotectedHeader(HeaderType, "JWT"))
+	assert.Error(t, token.WithUnprotectedHeader(headerCritical, []string{"route"}))
+	assert.Error(t, token.WithHeader("route", "relay-2"))
+
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+	assert.NotContains(t, token.GetHeader(), HeaderKey("route"))
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	assert.Empty(t, parsed.GetUnprotectedHeader())
+
+	fullString, err := token.FullSerialize()
+	assert.NoError(t, err)
+	parsed, err = Parse(fullString)
+	assert.NoError(t, err)
+	assert.Equal(t, "relay-1", parsed.GetUnprotectedHeader()["route"])
+	assert.NotContains(t, parsed.GetHeader(), HeaderKey("route"))
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	// collision between protected and unprotected headers
+	var raw map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(fullString), &raw))
+	raw["header"] = map[string]interface{}{"typ": "JWT"}
+	collision, err := json.Marshal(raw)
+	assert.NoError(t, err)
+	_, err = Parse(string(collision))
+	assert.Error(t, err)
+}
+
+func TestToken_BaselineFullSerialization(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	// earlier versions copied protected headers to unprotected ones and encoded segments with standard base64
+	var headers map[string]interface{}
+	assert.NoError(t, json.Unmarshal(token.raw.Protected, &headers))
+	baseline, err := json.Marshal(struct {
+		Payload   []byte                 `json:"payload,omitempty"`
+		Protected []byte                 `json:"protected,omitempty"`
+		Header    map[string]interface{} `json:"header,omitempty"`
+		ZKP       []byte                 `json:"zkp,omitempty"`
+	}{token.raw.Payload, token.raw.Protected, headers, token.raw.ZKP})
+	assert.NoError(t, err)
+
+	parsed, err := Parse(string(baseline))
+	assert.NoError(t, err)
+	assert.Empty(t, parsed.GetUnprotectedHeader())
+	assert.Equal(t, token.ZkProof, parsed.ZkProof)
+	compact, err := FullToCompact(string(baseline))
+	assert.NoError(t, err)
+	assert.Equal(t, authV2Token, compact)
+
+	// duplicate of protected set with different value is a collision
+	headers[string(HeaderType)] = "JWT"
+	conflict, err := json.Marshal(struct {
+		Payload   []byte                 `json:"payload,omitempty"`
+		Protected []byte                 `json:"protected,omitempty"`
+		Header    map[string]interface{} `json:"header,omitempty"`
+		ZKP       []byte                 `json:"zkp,omitempty"`
+	}{token.raw.Payload, token.raw.Protected, headers, token.raw.ZKP})
+	assert.NoError(t, err)
+	_, err = Parse(string(conflict))
+	assert.ErrorContains(t, err, "is both protected and unprotected")
+
+	// partial duplicate with the same value is a collision too
+	partial, err := json.Marshal(struct {
+		Payload   []byte                 `json:"payload,omitempty"`
+		Protected []byte                 `json:"protected,omitempty"`
+		Header    map[string]interface{} `json:"header,omitempty"`
+		ZKP       []byte                 `json:"zkp,omitempty"`
+	}{token.raw.Payload, token.raw.Protected, map[string]interface{}{"alg": token.Alg}, token.raw.ZKP})
+	assert.NoError(t, err)
+	_, err = Parse(string(partial))
+	assert.EqualError(t, err, "iden3/go-jwz: header alg is both protected and unprotected")
+}
+
+func TestToken_FullSerializeBase64URL(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	fullString, err := token.FullSerialize()
+	assert.NoError(t, err)
+	assert.NotContains(t, fullString, "+")
+	assert.NotContains(t, fullString, "/")
+	assert.NotContains(t, fullString, "=")
+
+	var j map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(fullString), &j))
+	parts := strings.Split(authV2Token, ".")
+	assert.Equal(t, parts[0], j["protected"])
+	assert.Equal(t, parts[1], j["payload"])
+	assert.Equal(t, parts[2], j["zkp"])
+
+	// full serialization with standard base64 is parsed as well
+	legacy, err := json.Marshal(struct {
+		Payload   []byte `json:"payload"`
+		Protected []byte `json:"protected"`
+		ZKP       []byte `json:"zkp"`
+	}{token.raw.Payload, token.raw.Protected, token.raw.ZKP})
+	assert.NoError(t, err)
+
+	for _, s := range []string{fullString, string(legacy)} {
+		parsed, err := Parse(s)
+		assert.NoError(t, err)
+		assert.Equal(t, token.raw.Payload, parsed.raw.Payload)
+		assert.Equal(t, token.ZkProof, parsed.ZkProof)
+
+		compact, err := parsed.CompactSerialize()
+		assert.NoError(t, err)
+		assert.Equal(t, authV2Token, compact)
+	}
+
+	// unencoded payload is a plain string in json serialization
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("my message"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithUnencodedPayload())
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	fullString, err = token.FullSerialize()
+	assert.NoError(t, err)
+	assert.Contains(t, fullString, `"payload":"my message"`)
+	parsed, err := Parse(fullString)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("my message"), parsed.GetPayload())
+}
+
+func TestToken_LosslessConversion(t *testing.T) {
+	// protected headers are not in the order json.Marshal would produce
+	protected := base64.RawURLEncoding.EncodeToString(
+		[]byte(`{"typ":"JWZ", "circuitId":"mockCircuit","alg":"mock","crit":["circuitId"]}`))
+	payload := base64.RawURLEncoding.EncodeToString([]byte("mymessage"))
+	msgHash, err := Hash([]byte(protected + "." + payload))
+	assert.NoError(t, err)
+	// proof with fields in non-canonical order and spaces
+	zkp := base64.RawURLEncoding.EncodeToString([]byte(
+		`{"pub_signals": ["` + msgHash.String() + `"], "proof": {"protocol":"mock","pi_a":["1","2","1"]}}`))
+	compact := protected + "." + payload + "." + zkp
+
+	parsed, err := Parse(compact)
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+
+	reserialized, err := parsed.CompactSerialize()
+	assert.NoError(t, err)
+	assert.Equal(t, compact, reserialized)
+
+	for _, c := range []string{compact, authV2Token} {
+		full, err := CompactToFull(c)
+		assert.NoError(t, err)
+
+		parsed, err = Parse(full)
+		assert.NoError(t, err)
+		reference, err := Parse(c)
+		assert.NoError(t, err)
+		fullHash, err := parsed.GetMessageHash()
+		assert.NoError(t, err)
+		compactHash, err := reference.GetMessageHash()
+		assert.NoError(t, err)
+		assert.Equal(t, compactHash, fullHash)
+
+		back, err := FullToCompact(full)
+		assert.NoError(t, err)
+		assert.Equal(t, c, back)
+	}
+
+	// non canonical base64 is rejected, as it can't be reproduced
+	_, err = Parse(protected + "." + "bXk" + "." + zkp)
+	assert.NoError(t, err)
+	_, err = Parse(protected + "." + "bXl" + "." + zkp)
+	assert.Error(t, err)
+
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithUnprotectedHeader("route", "relay-1"))
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	full, err := token.FullSerialize()
+	assert.NoError(t, err)
+	_, err = FullToCompact(full)
+	assert.Error(t, err)
+}
+
+func TestToken_Immutable(t *testing.T) {
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, token.WithHeader("route", "relay-1"), errTokenSealed)
+	assert.ErrorIs(t, token.WithUnprotectedHeader("route", "relay-1"), errTokenSealed)
+	assert.ErrorIs(t, token.WithUnencodedPayload(), errTokenSealed)
+
+	_, err = token.Prove(nil, nil)
+	assert.ErrorIs(t, err, errTokenSealed)
+
+	// returned headers are copies
+	token.GetHeader()[HeaderType] = "JWT"
+	assert.Equal(t, "JWZ", token.GetType())
+
+	parsed, err := Parse(tokenString)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, parsed.WithHeader("route", "relay-1"), errTokenSealed)
+	parsedHash, err := parsed.GetMessageHash()
+	assert.NoError(t, err)
+	_, err = parsed.Prove(nil, nil)
+	assert.ErrorIs(t, err, errTokenSealed)
+	_, err = parsed.ProveWithPayloadReader(strings.NewReader("mymessage"), nil, nil)
+	assert.ErrorIs(t, err, errTokenSealed)
+	// rejected prove doesn't change the parsed token
+	msgHash, err := parsed.GetMessageHash()
+	assert.NoError(t, err)
+	assert.Equal(t, parsedHash, msgHash)
+
+	// parsed token is shared across goroutines
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			isValid, err := parsed.Verify(nil)
+			assert.NoError(t, err)
+			assert.True(t, isValid)
+			s, err := parsed.CompactSerialize()
+			assert.NoError(t, err)
+			assert.Equal(t, tokenString, s)
+		}()
+	}
+	wg.Wait()
+}
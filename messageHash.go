@@ -0,0 +1,213 @@
+package jwz
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"hash"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/constants"
+	"github.com/iden3/go-iden3-crypto/poseidon"
+	"golang.org/x/crypto/sha3"
+)
+
+const (
+	// MessageHashPoseidon is poseidon sponge hash over the message split into 31 bytes chunks
+	// (same as poseidon.HashBytes), it's cheap to reproduce inside of the circuit.
+	MessageHashPoseidon = "poseidon"
+	// MessageHashKeccak256 is keccak256 hash of the message reduced to the field,
+	// it's cheap to reproduce in EVM.
+	MessageHashKeccak256 = "keccak256"
+	// MessageHashSHA256TwoLimbs is poseidon hash of sha256 hash of the message split into two 128 bits field elements,
+	// so no information of the hash is lost with modular reduction.
+	MessageHashSHA256TwoLimbs = "sha256-2limbs-poseidon"
+	// MessageHashWideV2 is domain separated sha512 hash of the message with wide reduction to the field.
+	// Unlike MessageHashSHA256Poseidon, result is uniformly distributed in the field (bias is less than 2^-250),
+	// so there are no collisions between digests x and x+Q.
+	MessageHashWideV2 = "sha512-wide-v2"
+)
+
+// messageHashWideV2DST is domain separation tag of MessageHashWideV2
+const messageHashWideV2DST = "iden3/go-jwz:" + MessageHashWideV2
+
+const (
+	poseidonChunkSize = 31
+	poseidonFrameSize = 16
+)
+
+// nolint : used for init message hash algs
+func init() {
+	RegisterMessageHasher(MessageHashPoseidon, func() MessageHasher {
+		return new(poseidonSpongeHasher)
+	})
+	RegisterMessageHasher(MessageHashKeccak256, func() MessageHasher {
+		return &keccak256Hasher{h: sha3.NewLegacyKeccak256()}
+	})
+	RegisterMessageHasher(MessageHashSHA256TwoLimbs, func() MessageHasher {
+		return &sha256TwoLimbsHasher{h: sha256.New()}
+	})
+	RegisterMessageHasher(MessageHashWideV2, func() MessageHasher {
+		w := &wideReductionHasher{h: sha512.New()}
+		w.Reset()
+		return w
+	})
+}
+
+// poseidonSpongeHasher computes poseidon sponge hash of the message without buffering the whole message
+type poseidonSpongeHasher struct {
+	chunk  []byte
+	frame  []*big.Int
+	k      int
+	dirty  bool
+	length int
+}
+
+// Write adds more data to the running hash.
+func (p *poseidonSpongeHasher) Write(b []byte) (int, error) {
+	n := len(b)
+	for len(b) > 0 {
+		l := poseidonChunkSize - len(p.chunk)
+		if l > len(b) {
+			l = len(b)
+		}
+		p.chunk = append(p.chunk, b[:l]...)
+		b = b[l:]
+		if len(p.chunk) == poseidonChunkSize {
+			err := p.absorb(new(big.Int).SetBytes(p.chunk))
+			if err != nil {
+				return 0, err
+			}
+			p.chunk = p.chunk[:0]
+		}
+	}
+	p.length += n
+	return n, nil
+}
+
+// absorb adds field element to the sponge frame and hashes the frame once it's full
+func (p *poseidonSpongeHasher) absorb(e *big.Int) error {
+	if p.frame == nil {
+		p.frame = newPoseidonFrame(nil)
+	}
+	p.dirty = true
+	p.frame[p.k] = e
+	if p.k < poseidonFrameSize-1 {
+		p.k++
+		return nil
+	}
+	h, err := poseidon.Hash(p.frame)
+	if err != nil {
+		return err
+	}
+	p.frame = newPoseidonFrame(h)
+	p.k = 1
+	p.dirty = false
+	return nil
+}
+
+// Reset resets the hasher to its initial state.
+func (p *poseidonSpongeHasher) Reset() {
+	*p = poseidonSpongeHasher{}
+}
+
+// Sum returns poseidon sponge hash of all bytes written so far.
+func (p *poseidonSpongeHasher) Sum() (*big.Int, error) {
+	if p.length == 0 {
+		return nil, errors.New("iden3/go-jwz: can't hash empty message")
+	}
+
+	frame := newPoseidonFrame(nil)
+	copy(frame, p.frame)
+	dirty := p.dirty
+	if len(p.chunk) != 0 {
+		// the last chunk of the message is zero padded to 31 bytes
+		var buf [poseidonChunkSize]byte
+		copy(buf[:], p.chunk)
+		frame[p.k] = new(big.Int).SetBytes(buf[:])
+		dirty = true
+	}
+	if !dirty {
+		return new(big.Int).Set(p.frame[0]), nil
+	}
+	return poseidon.Hash(frame)
+}
+
+func newPoseidonFrame(first *big.Int) []*big.Int {
+	frame := make([]*big.Int, poseidonFrameSize)
+	for i := range frame {
+		frame[i] = new(big.Int)
+	}
+	if first != nil {
+		frame[0] = first
+	}
+	return frame
+}
+
+// keccak256Hasher computes keccak256 hash of the message reduced to the field
+type keccak256Hasher struct {
+	h hash.Hash
+}
+
+// Write adds more data to the running hash.
+func (k *keccak256Hasher) Write(p []byte) (int, error) {
+	return k.h.Write(p)
+}
+
+// Reset resets the hasher to its initial state.
+func (k *keccak256Hasher) Reset() {
+	k.h.Reset()
+}
+
+// Sum returns big endian keccak256 hash of the written bytes modulo field order.
+func (k *keccak256Hasher) Sum() (*big.Int, error) {
+	bi := new(big.Int).SetBytes(k.h.Sum(nil))
+	return bi.Mod(bi, constants.Q), nil
+}
+
+// sha256TwoLimbsHasher computes poseidon hash of two 128 bits limbs of sha256 hash of the message
+type sha256TwoLimbsHasher struct {
+	h hash.Hash
+}
+
+// Write adds more data to the running hash.
+func (s *sha256TwoLimbsHasher) Write(p []byte) (int, error) {
+	return s.h.Write(p)
+}
+
+// Reset resets the hasher to its initial state.
+func (s *sha256TwoLimbsHasher) Reset() {
+	s.h.Reset()
+}
+
+// Sum returns poseidon hash of high and low big endian 128 bits limbs of sha256 hash of the written bytes.
+func (s *sha256TwoLimbsHasher) Sum() (*big.Int, error) {
+	b := s.h.Sum(nil)
+	hi := new(big.Int).SetBytes(b[:sha256.Size/2])
+	lo := new(big.Int).SetBytes(b[sha256.Size/2:])
+	return poseidon.Hash([]*big.Int{hi, lo})
+}
+
+// wideReductionHasher computes domain separated sha512 hash of the message reduced to the field
+type wideReductionHasher struct {
+	h hash.Hash
+}
+
+// Write adds more data to the running hash.
+func (w *wideReductionHasher) Write(p []byte) (int, error) {
+	return w.h.Write(p)
+}
+
+// Reset resets the hasher to its initial state with domain separation tag written.
+func (w *wideReductionHasher) Reset() {
+	w.h.Reset()
+	// length prefixed tag, so it can't be confused with the message
+	_, _ = w.h.Write([]byte{byte(len(messageHashWideV2DST))})
+	_, _ = w.h.Write([]byte(messageHashWideV2DST))
+}
+
+// Sum returns big endian 512 bits hash of the written bytes modulo field order.
+func (w *wideReductionHasher) Sum() (*big.Int, error) {
+	bi := new(big.Int).SetBytes(w.h.Sum(nil))
+	return bi.Mod(bi, constants.Q), nil
+}
@@ -0,0 +1,404 @@
+package jwz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-rapidsnark/types"
+)
+
+// ProofEncodingBN254Compressed is compact encoding of groth16 proof over BN254 curve:
+// compressed A (32 bytes), B (64 bytes), C (32 bytes) points followed by 32 bytes big endian public signals.
+// Point is encoded with its x coordinate (x.c1 || x.c0 for G2) where two most significant bits are flags:
+// 0x80 - point at infinity, 0x40 - y is lexicographically largest of two roots.
+const ProofEncodingBN254Compressed = "bn254-compressed"
+
+const (
+	g1CompressedSize = 32
+	g2CompressedSize = 64
+	proofHeadSize    = 2*g1CompressedSize + g2CompressedSize
+
+	flagInfinity byte = 0x80
+	flagLargestY byte = 0x40
+	flagsMask         = flagInfinity | flagLargestY
+)
+
+var (
+	// bn254P is the prime of BN254 base field
+	bn254P, _ = new(big.Int).SetString(
+		"21888242871839275222246405745257275088696311157297823662689037894645226208583", 10)
+	bn254PMinus1Half = new(big.Int).Rsh(bn254P, 1)
+	bn254G1B         = big.NewInt(3)
+	// bn254G2B is 3/(9+u), the twist curve constant
+	bn254G2B = fp2{
+		c0: bigFromString("19485874751759354771024239261021720505790618469301721065564631296452457478373"),
+		c1: bigFromString("266929791119991161246907387137283842545076965332900288569378510910307636690"),
+	}
+)
+
+func bigFromString(s string) *big.Int {
+	i, _ := new(big.Int).SetString(s, 10)
+	return i
+}
+
+// WithProofEncoding sets compact encoding of proof segment, that is signalled with 'zkpEnc' header.
+func (token *Token) WithProofEncoding(encoding string) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if encoding != ProofEncodingBN254Compressed {
+		return fmt.Errorf("iden3/go-jwz: unsupported proof encoding %s", encoding)
+	}
+	token.header[headerProofEncoding] = encoding
+	token.addCritical(headerProofEncoding)
+	return nil
+}
+
+// GetProofEncoding returns encoding of proof segment, empty string is json encoding
+func (token *Token) GetProofEncoding() string {
+	encoding, _ := token.header[headerProofEncoding].(string)
+	return encoding
+}
+
+// marshalProof encodes proof according to the proof encoding header
+func marshalProof(encoding string, proof *types.ZKProof) ([]byte, error) {
+	switch encoding {
+	case "":
+		return json.Marshal(proof)
+	case ProofEncodingBN254Compressed:
+		return compressProof(proof)
+	default:
+		return nil, fmt.Errorf("iden3/go-jwz: unsupported proof encoding %s", encoding)
+	}
+}
+
+// unmarshalProof decodes proof according to the proof encoding header
+func unmarshalProof(encoding string, data []byte) (*types.ZKProof, error) {
+	switch encoding {
+	case "":
+		var proof *types.ZKProof
+		err := json.Unmarshal(data, &proof)
+		return proof, err
+	case ProofEncodingBN254Compressed:
+		return decompressProof(data)
+	default:
+		return nil, fmt.Errorf("iden3/go-jwz: unsupported proof encoding %s", encoding)
+	}
+}
+
+func compressProof(proof *types.ZKProof) ([]byte, error) {
+	if proof.Proof == nil || proof.Proof.Protocol != Groth16 {
+		return nil, errors.New("iden3/go-jwz: only groth16 proof can be compressed")
+	}
+	out := make([]byte, 0, proofHeadSize+fieldElementSize*len(proof.PubSignals))
+
+	a, err := compressG1(proof.Proof.A)
+	if err != nil {
+		return nil, err
+	}
+	b, err := compressG2(proof.Proof.B)
+	if err != nil {
+		return nil, err
+	}
+	c, err := compressG1(proof.Proof.C)
+	if err != nil {
+		return nil, err
+	}
+	out = append(append(append(out, a...), b...), c...)
+
+	for _, s := range proof.PubSignals {
+		e, err := fieldElementFromString(s)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, e...)
+	}
+	return out, nil
+}
+
+func decompressProof(data []byte) (*types.ZKProof, error) {
+	if len(data) < proofHeadSize || (len(data)-proofHeadSize)%fieldElementSize != 0 {
+		return nil, errors.New("iden3/go-jwz: invalid length of compressed proof")
+	}
+
+	var err error
+	proof := &types.ZKProof{Proof: &types.ProofData{Protocol: Groth16}}
+	proof.Proof.A, err = decompressG1(data[:g1CompressedSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[g1CompressedSize:]
+	proof.Proof.B, err = decompressG2(data[:g2CompressedSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[g2CompressedSize:]
+	proof.Proof.C, err = decompressG1(data[:g1CompressedSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[g1CompressedSize:]
+
+	proof.PubSignals = make([]string, 0, len(data)/fieldElementSize)
+	for ; len(data) > 0; data = data[fieldElementSize:] {
+		proof.PubSignals = append(proof.PubSignals, new(big.Int).SetBytes(data[:fieldElementSize]).String())
+	}
+	return proof, nil
+}
+
+// fieldElementFromString converts decimal string to 32 bytes big endian presentation
+func fieldElementFromString(s string) ([]byte, error) {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok || i.Sign() < 0 || i.BitLen() > 8*fieldElementSize {
+		return nil, fmt.Errorf("iden3/go-jwz: invalid field element %s", s)
+	}
+	return i.FillBytes(make([]byte, fieldElementSize)), nil
+}
+
+// coordinate converts decimal string to the element of base field
+func coordinate(s string) (*big.Int, error) {
+	i, ok := new(big.Int).SetString(s, 10)
+	if !ok || i.Sign() < 0 || i.Cmp(bn254P) >= 0 {
+		return nil, fmt.Errorf("iden3/go-jwz: invalid coordinate %s", s)
+	}
+	return i, nil
+}
+
+// compressG1 compresses affine point in snarkjs presentation [x, y, "1"]
+func compressG1(p []string) ([]byte, error) {
+	if len(p) != 3 {
+		return nil, errors.New("iden3/go-jwz: G1 point must have three coordinates")
+	}
+	out := make([]byte, g1CompressedSize)
+	if p[2] == "0" {
+		out[0] = flagInfinity
+		return out, nil
+	}
+	if p[2] != "1" {
+		return nil, errors.New("iden3/go-jwz: G1 point is not normalized")
+	}
+	x, err := coordinate(p[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := coordinate(p[1])
+	if err != nil {
+		return nil, err
+	}
+	if new(big.Int).Exp(y, big.NewInt(2), bn254P).Cmp(g1RHS(x)) != 0 {
+		return nil, errors.New("iden3/go-jwz: G1 point is not on curve")
+	}
+
+	x.FillBytes(out)
+	if y.Cmp(bn254PMinus1Half) > 0 {
+		out[0] |= flagLargestY
+	}
+	return out, nil
+}
+
+// decompressG1 returns affine point in snarkjs presentation [x, y, "1"]
+func decompressG1(b []byte) ([]string, error) {
+	if b[0]&flagInfinity != 0 {
+		if !isInfinityEncoding(b) {
+			return nil, errors.New("iden3/go-jwz: non-canonical encoding of G1 point at infinity")
+		}
+		return []string{"0", "1", "0"}, nil
+	}
+	x := new(big.Int).SetBytes(append([]byte{b[0] &^ flagsMask}, b[1:]...))
+	if x.Cmp(bn254P) >= 0 {
+		return nil, errors.New("iden3/go-jwz: invalid compressed G1 point")
+	}
+
+	rhs := g1RHS(x)
+	y := new(big.Int).Exp(rhs, new(big.Int).Rsh(new(big.Int).Add(bn254P, big.NewInt(1)), 2), bn254P)
+	if new(big.Int).Exp(y, big.NewInt(2), bn254P).Cmp(rhs) != 0 {
+		return nil, errors.New("iden3/go-jwz: compressed G1 point is not on curve")
+	}
+	if (y.Cmp(bn254PMinus1Half) > 0) != (b[0]&flagLargestY != 0) {
+		y.Sub(bn254P, y)
+	}
+	return []string{x.String(), y.String(), "1"}, nil
+}
+
+// isInfinityEncoding checks that point at infinity has no other bits than infinity flag set,
+// so every point has the only encoding
+func isInfinityEncoding(b []byte) bool {
+	if b[0] != flagInfinity {
+		return false
+	}
+	for _, v := range b[1:] {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// g1RHS returns x^3 + 3
+func g1RHS(x *big.Int) *big.Int {
+	rhs := new(big.Int).Exp(x, big.NewInt(3), bn254P)
+	rhs.Add(rhs, bn254G1B)
+	return rhs.Mod(rhs, bn254P)
+}
+
+// compressG2 compresses affine point in snarkjs presentation [[x0, x1], [y0, y1], ["1", "0"]]
+func compressG2(p [][]string) ([]byte, error) {
+	if len(p) != 3 || len(p[0]) != 2 || len(p[1]) != 2 || len(p[2]) != 2 {
+		return nil, errors.New("iden3/go-jwz: G2 point must have three coordinates")
+	}
+	out := make([]byte, g2CompressedSize)
+	if p[2][0] == "0" && p[2][1] == "0" {
+		out[0] = flagInfinity
+		return out, nil
+	}
+	if p[2][0] != "1" || p[2][1] != "0" {
+		return nil, errors.New("iden3/go-jwz: G2 point is not normalized")
+	}
+	x, err := fp2FromStrings(p[0])
+	if err != nil {
+		return nil, err
+	}
+	y, err := fp2FromStrings(p[1])
+	if err != nil {
+		return nil, err
+	}
+	if !y.mul(y).equal(g2RHS(x)) {
+		return nil, errors.New("iden3/go-jwz: G2 point is not on curve")
+	}
+
+	x.c1.FillBytes(out[:fieldElementSize])
+	x.c0.FillBytes(out[fieldElementSize:])
+	if y.isLargest() {
+		out[0] |= flagLargestY
+	}
+	return out, nil
+}
+
+// decompressG2 returns affine point in snarkjs presentation [[x0, x1], [y0, y1], ["1", "0"]]
+func decompressG2(b []byte) ([][]string, error) {
+	if b[0]&flagInfinity != 0 {
+		if !isInfinityEncoding(b) {
+			return nil, errors.New("iden3/go-jwz: non-canonical encoding of G2 point at infinity")
+		}
+		return [][]string{{"0", "0"}, {"1", "0"}, {"0", "0"}}, nil
+	}
+	x := fp2{
+		c0: new(big.Int).SetBytes(b[fieldElementSize:]),
+		c1: new(big.Int).SetBytes(append([]byte{b[0] &^ flagsMask}, b[1:fieldElementSize]...)),
+	}
+	if x.c0.Cmp(bn254P) >= 0 || x.c1.Cmp(bn254P) >= 0 {
+		return nil, errors.New("iden3/go-jwz: invalid compressed G2 point")
+	}
+
+	y, ok := g2RHS(x).sqrt()
+	if !ok {
+		return nil, errors.New("iden3/go-jwz: compressed G2 point is not on curve")
+	}
+	if y.isLargest() != (b[0]&flagLargestY != 0) {
+		y = y.neg()
+	}
+	return [][]string{
+		{x.c0.String(), x.c1.String()},
+		{y.c0.String(), y.c1.String()},
+		{"1", "0"},
+	}, nil
+}
+
+// g2RHS returns x^3 + 3/(9+u)
+func g2RHS(x fp2) fp2 {
+	return x.mul(x).mul(x).add(bn254G2B)
+}
+
+// fp2 is element c0 + c1*u of quadratic extension of BN254 base field, where u^2 = -1
+type fp2 struct {
+	c0, c1 *big.Int
+}
+
+func fp2FromStrings(s []string) (fp2, error) {
+	c0, err := coordinate(s[0])
+	if err != nil {
+		return fp2{}, err
+	}
+	c1, err := coordinate(s[1])
+	if err != nil {
+		return fp2{}, err
+	}
+	return fp2{c0, c1}, nil
+}
+
+func (a fp2) add(b fp2) fp2 {
+	return fp2{
+		c0: mod(new(big.Int).Add(a.c0, b.c0)),
+		c1: mod(new(big.Int).Add(a.c1, b.c1)),
+	}
+}
+
+func (a fp2) mul(b fp2) fp2 {
+	c0 := new(big.Int).Mul(a.c0, b.c0)
+	c0.Sub(c0, new(big.Int).Mul(a.c1, b.c1))
+	c1 := new(big.Int).Mul(a.c0, b.c1)
+	c1.Add(c1, new(big.Int).Mul(a.c1, b.c0))
+	return fp2{mod(c0), mod(c1)}
+}
+
+func (a fp2) neg() fp2 {
+	return fp2{mod(new(big.Int).Neg(a.c0)), mod(new(big.Int).Neg(a.c1))}
+}
+
+// conj returns a^p
+func (a fp2) conj() fp2 {
+	return fp2{new(big.Int).Set(a.c0), mod(new(big.Int).Neg(a.c1))}
+}
+
+func (a fp2) exp(e *big.Int) fp2 {
+	res := fp2{big.NewInt(1), big.NewInt(0)}
+	for i := e.BitLen() - 1; i >= 0; i-- {
+		res = res.mul(res)
+		if e.Bit(i) == 1 {
+			res = res.mul(a)
+		}
+	}
+	return res
+}
+
+func (a fp2) equal(b fp2) bool {
+	return a.c0.Cmp(b.c0) == 0 && a.c1.Cmp(b.c1) == 0
+}
+
+// isLargest compares c1, or c0 if c1 is zero, with (p-1)/2
+func (a fp2) isLargest() bool {
+	if a.c1.Sign() != 0 {
+		return a.c1.Cmp(bn254PMinus1Half) > 0
+	}
+	return a.c0.Cmp(bn254PMinus1Half) > 0
+}
+
+// sqrt returns square root for p = 3 mod 4 (Algorithm 9 of https://eprint.iacr.org/2012/685.pdf)
+func (a fp2) sqrt() (fp2, bool) {
+	minusOne := fp2{new(big.Int).Sub(bn254P, big.NewInt(1)), big.NewInt(0)}
+
+	a1 := a.exp(new(big.Int).Rsh(new(big.Int).Sub(bn254P, big.NewInt(3)), 2))
+	alpha := a1.mul(a1).mul(a)
+	if alpha.conj().mul(alpha).equal(minusOne) {
+		return fp2{}, false
+	}
+	x0 := a1.mul(a)
+
+	var x fp2
+	if alpha.equal(minusOne) {
+		x = fp2{big.NewInt(0), big.NewInt(1)}.mul(x0)
+	} else {
+		b := alpha.add(fp2{big.NewInt(1), big.NewInt(0)}).exp(bn254PMinus1Half)
+		x = b.mul(x0)
+	}
+	if !x.mul(x).equal(a) {
+		return fp2{}, false
+	}
+	return x, true
+}
+
+func mod(i *big.Int) *big.Int {
+	return i.Mod(i, bn254P)
+}
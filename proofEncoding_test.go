@@ -0,0 +1,115 @@
+package jwz
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/iden3/go-rapidsnark/verifier"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressProof(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	compressed, err := compressProof(token.ZkProof)
+	assert.NoError(t, err)
+	assert.Len(t, compressed, 128+32*len(token.ZkProof.PubSignals))
+
+	proof, err := decompressProof(compressed)
+	assert.NoError(t, err)
+	assert.Equal(t, token.ZkProof, proof)
+
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+	assert.NoError(t, verifier.VerifyGroth16(*proof, verificationKey))
+
+	_, err = decompressProof(compressed[:len(compressed)-1])
+	assert.Error(t, err)
+}
+
+func TestCompressPoints(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	// negated points have other root of y
+	a := token.ZkProof.Proof.A
+	y := new(big.Int).Sub(bn254P, bigFromString(a[1]))
+	for _, p := range [][]string{a, {a[0], y.String(), "1"}, {"0", "1", "0"}} {
+		c, err := compressG1(p)
+		assert.NoError(t, err)
+		d, err := decompressG1(c)
+		assert.NoError(t, err)
+		assert.Equal(t, p, d)
+	}
+
+	b := token.ZkProof.Proof.B
+	negB := fp2{bigFromString(b[1][0]), bigFromString(b[1][1])}.neg()
+	for _, p := range [][][]string{b, {b[0], {negB.c0.String(), negB.c1.String()}, {"1", "0"}},
+		{{"0", "0"}, {"1", "0"}, {"0", "0"}}} {
+		c, err := compressG2(p)
+		assert.NoError(t, err)
+		d, err := decompressG2(c)
+		assert.NoError(t, err)
+		assert.Equal(t, p, d)
+	}
+
+	// point at infinity has the only encoding
+	for _, flags := range []byte{flagInfinity | flagLargestY, flagInfinity | 0x01} {
+		c := make([]byte, g1CompressedSize)
+		c[0] = flags
+		_, err = decompressG1(c)
+		assert.Error(t, err)
+		c = make([]byte, g2CompressedSize)
+		c[0] = flags
+		_, err = decompressG2(c)
+		assert.Error(t, err)
+	}
+	c := make([]byte, g1CompressedSize)
+	c[0], c[g1CompressedSize-1] = flagInfinity, 1
+	_, err = decompressG1(c)
+	assert.Error(t, err)
+	c = make([]byte, g2CompressedSize)
+	c[0], c[g2CompressedSize-1] = flagInfinity, 1
+	_, err = decompressG2(c)
+	assert.Error(t, err)
+
+	_, err = compressG1([]string{a[0], "5", "1"})
+	assert.Error(t, err)
+	_, err = compressG1([]string{a[0], a[1], "2"})
+	assert.Error(t, err)
+	_, err = compressProof(&types.ZKProof{Proof: &types.ProofData{Protocol: "plonk"}})
+	assert.Error(t, err)
+}
+
+func TestToken_WithProofEncoding(t *testing.T) {
+	token, err := NewWithPayload(ProvingMethodGroth16AuthV2Instance, []byte("mymessage"), MockPrepareAuthV2Inputs)
+	assert.NoError(t, err)
+	assert.Error(t, token.WithProofEncoding("unknown"))
+	assert.NoError(t, token.WithProofEncoding(ProofEncodingBN254Compressed))
+	assert.Equal(t, ProofEncodingBN254Compressed, token.GetProofEncoding())
+
+	// prove is emulated with existing proof, as proving key is not available
+	parsed, err := Parse(authV2Token)
+	assert.NoError(t, err)
+	assert.NoError(t, token.protectHeaders())
+	token.ZkProof = parsed.ZkProof
+	token.raw.ZKP, err = marshalProof(token.GetProofEncoding(), parsed.ZkProof)
+	assert.NoError(t, err)
+
+	compact, err := token.CompactSerialize()
+	assert.NoError(t, err)
+	assert.Less(t, len(compact), len(authV2Token)/2)
+
+	decoded, err := Parse(compact)
+	assert.NoError(t, err)
+	assert.Equal(t, parsed.ZkProof, decoded.ZkProof)
+
+	data, err := decoded.BinarySerialize()
+	assert.NoError(t, err)
+	decoded, err = ParseBinary(data)
+	assert.NoError(t, err)
+	assert.Equal(t, parsed.ZkProof, decoded.ZkProof)
+}
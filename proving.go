@@ -1,6 +1,8 @@
 package jwz
 
 import (
+	"context"
+	"fmt"
 	"sync"
 
 	"github.com/iden3/go-circuits/v2"
@@ -18,9 +20,6 @@ func NewProvingMethodAlg(alg, circuitID string) ProvingMethodAlg {
 	return ProvingMethodAlg{Alg: alg, CircuitID: circuitID}
 }
 
-var provingMethods = map[ProvingMethodAlg]func() ProvingMethod{}
-var provingMethodLock = new(sync.RWMutex)
-
 // ProvingMethod can be used add new methods for signing or verifying tokens.
 type ProvingMethod interface {
 	Verify(messageHash []byte, proof *types.ZKProof, verificationKey []byte) error // Returns nil if proof is valid
@@ -29,35 +28,160 @@ type ProvingMethod interface {
 	CircuitID() string
 }
 
-// RegisterProvingMethod registers the "alg" name and a factory function for proving method.
-// This is typically done during init() in the method's implementation
-func RegisterProvingMethod(alg ProvingMethodAlg, f func() ProvingMethod) {
-	provingMethodLock.Lock()
-	defer provingMethodLock.Unlock()
-	provingMethods[alg] = f
+// ContextProvingMethod is implemented by proving methods that stop proving when the context is done
+type ContextProvingMethod interface {
+	ProveContext(ctx context.Context, inputs []byte, provingKey []byte, wasm []byte) (*types.ZKProof, error)
+}
+
+// MethodStatus is lifecycle status of proving method in the registry
+type MethodStatus int
+
+const (
+	// MethodActive is proving method that is accepted without restrictions
+	MethodActive MethodStatus = iota
+	// MethodDeprecated is proving method that is accepted, but verification reports warning
+	MethodDeprecated
+	// MethodDisabled is proving method that tokens can be parsed with, but verification is rejected
+	// unless disabled methods are explicitly allowed
+	MethodDisabled
+)
+
+// String returns name of the status
+func (s MethodStatus) String() string {
+	switch s {
+	case MethodActive:
+		return "active"
+	case MethodDeprecated:
+		return "deprecated"
+	case MethodDisabled:
+		return "disabled"
+	default:
+		return fmt.Sprintf("MethodStatus(%d)", int(s))
+	}
+}
+
+// Registry is a set of proving methods that are available for parsing and verification of tokens.
+// Registry is safe for concurrent use.
+type Registry struct {
+	lock    sync.RWMutex
+	methods map[ProvingMethodAlg]registryEntry
+}
+
+type registryEntry struct {
+	factory func() ProvingMethod
+	status  MethodStatus
+}
+
+// NewRegistry creates an empty Registry
+func NewRegistry() *Registry {
+	return &Registry{methods: map[ProvingMethodAlg]registryEntry{}}
+}
+
+// DefaultRegistry is the registry of proving methods implemented by the library, it's used
+// if registry is not set explicitly.
+var DefaultRegistry = NewRegistry()
+
+// Register registers the "alg" name and a factory function for active proving method.
+// Returns error if the method is already registered, so it can't be overridden silently.
+func (r *Registry) Register(alg ProvingMethodAlg, f func() ProvingMethod) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if _, ok := r.methods[alg]; ok {
+		return fmt.Errorf("iden3/go-jwz: proving method %s %s is already registered", alg.Alg, alg.CircuitID)
+	}
+	r.methods[alg] = registryEntry{factory: f, status: MethodActive}
+	return nil
+}
+
+// SetStatus changes status of registered proving method
+func (r *Registry) SetStatus(alg ProvingMethodAlg, status MethodStatus) error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	entry, ok := r.methods[alg]
+	if !ok {
+		return fmt.Errorf("iden3/go-jwz: proving method %s %s is not registered", alg.Alg, alg.CircuitID)
+	}
+	entry.status = status
+	r.methods[alg] = entry
+	return nil
+}
+
+// Status returns status of proving method, not registered methods are reported as active
+func (r *Registry) Status(alg ProvingMethodAlg) MethodStatus {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	return r.methods[alg].status
 }
 
-// GetProvingMethod retrieves a proving method from an "alg" string
-func GetProvingMethod(alg ProvingMethodAlg) (method ProvingMethod) {
-	provingMethodLock.RLock()
-	defer provingMethodLock.RUnlock()
-	if methodF, ok := provingMethods[alg]; ok {
-		method = methodF()
+// Get retrieves a proving method from an "alg" or nil if it's not registered
+func (r *Registry) Get(alg ProvingMethodAlg) (method ProvingMethod) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	if entry, ok := r.methods[alg]; ok {
+		method = entry.factory()
 	}
 	return
 }
 
-// GetAlgorithms returns a list of registered "alg" names
-func GetAlgorithms() (algs []ProvingMethodAlg) {
-	provingMethodLock.RLock()
-	defer provingMethodLock.RUnlock()
+// Algorithms returns a list of registered "alg" names
+func (r *Registry) Algorithms() (algs []ProvingMethodAlg) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
 
-	for alg := range provingMethods {
+	for alg := range r.methods {
 		algs = append(algs, alg)
 	}
 	return
 }
 
+// RegisterProvingMethod registers the "alg" name and a factory function for proving method in DefaultRegistry.
+// This is typically done during init() in the method's implementation. Already registered method
+// isn't overridden and error is returned instead.
+func RegisterProvingMethod(alg ProvingMethodAlg, f func() ProvingMethod) error {
+	return DefaultRegistry.Register(alg, f)
+}
+
+// GetProvingMethod retrieves a proving method from an "alg" string in DefaultRegistry
+func GetProvingMethod(alg ProvingMethodAlg) ProvingMethod {
+	return DefaultRegistry.Get(alg)
+}
+
+// GetAlgorithms returns a list of "alg" names registered in DefaultRegistry
+func GetAlgorithms() []ProvingMethodAlg {
+	return DefaultRegistry.Algorithms()
+}
+
+// ParseOption configures parsing of tokens
+type ParseOption func(opts *parseOptions)
+
+type parseOptions struct {
+	registry      *Registry
+	allowDisabled bool
+}
+
+// WithRegistry sets registry that proving method of parsed token is looked up in
+func WithRegistry(registry *Registry) ParseOption {
+	return func(opts *parseOptions) {
+		opts.registry = registry
+	}
+}
+
+// WithDisabledMethodsAllowed allows verification of tokens which proving method is disabled in the registry
+func WithDisabledMethodsAllowed() ParseOption {
+	return func(opts *parseOptions) {
+		opts.allowDisabled = true
+	}
+}
+
+// newParseOptions applies options over defaults
+func newParseOptions(opts []ParseOption) parseOptions {
+	o := parseOptions{registry: DefaultRegistry}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
 // ProofInputsPreparerHandlerFunc prepares inputs using hash message and circuit id
 type ProofInputsPreparerHandlerFunc func(hash []byte, circuitID circuits.CircuitID) ([]byte, error)
 
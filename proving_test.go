@@ -0,0 +1,93 @@
+package jwz
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	assert.Nil(t, r.Get(mockAlg))
+
+	assert.NoError(t, r.Register(mockAlg, func() ProvingMethod { return mockProvingMethodInstance }))
+	assert.EqualError(t, r.Register(mockAlg, func() ProvingMethod { return mockProvingMethodInstance }),
+		"iden3/go-jwz: proving method mock mockCircuit is already registered")
+	assert.Equal(t, mockProvingMethodInstance, r.Get(mockAlg))
+	assert.Equal(t, []ProvingMethodAlg{mockAlg}, r.Algorithms())
+
+	// built-in methods can't be overridden in default registry
+	assert.Error(t, DefaultRegistry.Register(AuthV2Groth16Alg, func() ProvingMethod { return mockProvingMethodInstance }))
+	assert.EqualError(t, RegisterProvingMethod(AuthV2Groth16Alg, func() ProvingMethod { return mockProvingMethodInstance }),
+		"iden3/go-jwz: proving method groth16 authV2 is already registered")
+	assert.Equal(t, ProvingMethodGroth16AuthV2Instance, GetProvingMethod(AuthV2Groth16Alg))
+}
+
+func TestRegistry_Parse(t *testing.T) {
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	// isolated registry doesn't contain mock method
+	empty := NewRegistry()
+	parsed, err := Parse(tokenString, WithRegistry(empty))
+	assert.NoError(t, err)
+	assert.Nil(t, parsed.Method)
+
+	_, err = NewVerifier(WithKeyStore(KeyStoreMap{}), WithParseOptions(WithRegistry(empty))).
+		Verify(context.Background(), tokenString)
+	assert.EqualError(t, err, "iden3/go-jwz: proving method mock mockCircuit is not registered")
+
+	parsed, err = Parse(tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, mockProvingMethodInstance, parsed.Method)
+}
+
+func TestRegistry_MethodStatus(t *testing.T) {
+	assert.Equal(t, MethodDeprecated, DefaultRegistry.Status(AuthGroth16Alg))
+	assert.Equal(t, MethodActive, DefaultRegistry.Status(AuthV2Groth16Alg))
+
+	r := NewRegistry()
+	assert.Error(t, r.SetStatus(mockAlg, MethodDisabled))
+	assert.NoError(t, r.Register(mockAlg, func() ProvingMethod { return mockProvingMethodInstance }))
+
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	tokenString, err := token.Prove(nil, nil)
+	assert.NoError(t, err)
+	keys := KeyStoreMap{mockAlg: []byte("{}")}
+
+	// deprecated method is accepted with warning
+	assert.NoError(t, r.SetStatus(mockAlg, MethodDeprecated))
+	res, err := NewVerifier(WithKeyStore(keys), WithParseOptions(WithRegistry(r))).Verify(context.Background(), tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"proving method mock mockCircuit is deprecated"}, res.Warnings)
+	// the same warning is available without the verifier
+	parsed, err := Parse(tokenString, WithRegistry(r))
+	assert.NoError(t, err)
+	assert.Equal(t, MethodDeprecated, parsed.MethodStatus())
+	assert.Equal(t, res.Warnings, parsed.Warnings())
+
+	// disabled method can be parsed, but verification is rejected
+	assert.NoError(t, r.SetStatus(mockAlg, MethodDisabled))
+	parsed, err = Parse(tokenString, WithRegistry(r))
+	assert.NoError(t, err)
+	assert.Equal(t, MethodDisabled, parsed.MethodStatus())
+	_, err = parsed.Verify(nil)
+	assert.ErrorIs(t, err, ErrMethodDisabled)
+	_, err = NewVerifier(WithKeyStore(keys), WithParseOptions(WithRegistry(r))).Verify(context.Background(), tokenString)
+	assert.ErrorIs(t, err, ErrMethodDisabled)
+
+	// unless disabled methods are explicitly allowed
+	parsed, err = Parse(tokenString, WithRegistry(r), WithDisabledMethodsAllowed())
+	assert.NoError(t, err)
+	isValid, err := parsed.Verify(nil)
+	assert.NoError(t, err)
+	assert.True(t, isValid)
+	res, err = NewVerifier(WithKeyStore(keys), WithParseOptions(WithRegistry(r), WithDisabledMethodsAllowed())).
+		Verify(context.Background(), tokenString)
+	assert.NoError(t, err)
+	assert.Empty(t, res.Warnings)
+}
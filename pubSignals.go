@@ -0,0 +1,43 @@
+package jwz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/iden3/go-circuits/v2"
+)
+
+// PubSignals returns public signals of the proof unmarshalled to the type that go-circuits registers
+// for the circuit of the token, e.g. *circuits.AuthV2PubSignals for authV2 circuit.
+// Number of public signals is validated by unmarshaller of the circuit.
+func (token *Token) PubSignals() (circuits.PubSignals, error) {
+	if token.ZkProof == nil {
+		return nil, errors.New("iden3/go-jwz: token has no proof")
+	}
+
+	circuitID := circuits.CircuitID(token.CircuitID)
+	circuit, err := circuits.GetCircuit(circuitID)
+	if err != nil {
+		return nil, fmt.Errorf("iden3/go-jwz: circuit %s: %w", circuitID, err)
+	}
+	typ := reflect.TypeOf(circuit.Output)
+	if typ == nil || typ.Kind() != reflect.Ptr {
+		return nil, fmt.Errorf("iden3/go-jwz: circuit %s has no public signals type", circuitID)
+	}
+	out, ok := reflect.New(typ.Elem()).Interface().(circuits.PubSignals)
+	if !ok {
+		return nil, fmt.Errorf("iden3/go-jwz: circuit %s has no public signals type", circuitID)
+	}
+
+	marshaledPubSignals, err := json.Marshal(token.ZkProof.PubSignals)
+	if err != nil {
+		return nil, err
+	}
+	err = out.PubSignalsUnmarshal(marshaledPubSignals)
+	if err != nil {
+		return nil, fmt.Errorf("iden3/go-jwz: invalid public signals of circuit %s: %w", circuitID, err)
+	}
+	return out, nil
+}
@@ -0,0 +1,55 @@
+package jwz
+
+import (
+	"testing"
+
+	"github.com/iden3/go-circuits/v2"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_PubSignals(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+	userID := token.ZkProof.PubSignals[0]
+
+	pubSignals, err := token.PubSignals()
+	assert.NoError(t, err)
+	outs, ok := pubSignals.(*circuits.AuthV2PubSignals)
+	assert.True(t, ok)
+	assert.Equal(t, "x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29", outs.UserID.String())
+
+	msgHash, err := token.GetMessageHash()
+	assert.NoError(t, err)
+	assert.Equal(t, msgHash, outs.Challenge.Bytes())
+
+	// signal count must match the circuit
+	token.ZkProof.PubSignals = append(token.ZkProof.PubSignals, "1")
+	_, err = token.PubSignals()
+	assert.EqualError(t, err,
+		"iden3/go-jwz: invalid public signals of circuit authV2: invalid number of Output values expected {3} got {4} ")
+
+	// circuit is not known to go-circuits
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	_, err = token.PubSignals()
+	assert.Error(t, err)
+
+	// any circuit registered in go-circuits is supported
+	token = &Token{
+		CircuitID: string(circuits.StateTransitionCircuitID),
+		ZkProof:   &types.ZKProof{PubSignals: []string{userID, "1", "2", "1"}},
+	}
+	pubSignals, err = token.PubSignals()
+	assert.NoError(t, err)
+	transition, ok := pubSignals.(*circuits.StateTransitionPubSignals)
+	assert.True(t, ok)
+	assert.Equal(t, outs.UserID, transition.UserID)
+	assert.True(t, transition.IsOldStateGenesis)
+
+	token.ZkProof.PubSignals = token.ZkProof.PubSignals[:3]
+	_, err = token.PubSignals()
+	assert.Error(t, err)
+}
@@ -0,0 +1,204 @@
+package jwz
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-circuits/v2"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+)
+
+var (
+	// ErrSenderMismatch is returned when 'from' claim of the token doesn't match the proven identity
+	ErrSenderMismatch = errors.New("iden3/go-jwz: 'from' claim doesn't match proven identity")
+	// ErrProfileNotAllowed is returned when the token proves profile identity, but genesis one is required
+	ErrProfileNotAllowed = errors.New("iden3/go-jwz: profile identity is not allowed")
+	// ErrGenesisNotAllowed is returned when the token proves genesis identity, but profile one is required
+	ErrGenesisNotAllowed = errors.New("iden3/go-jwz: genesis identity is not allowed")
+	// ErrSenderNotProven is returned when circuit of the token has no user id public signal
+	ErrSenderNotProven = errors.New("iden3/go-jwz: sender identity is not proven by the circuit")
+	// ErrIdentityUnknown is returned when genesis state of the identity proven by the token is not found
+	ErrIdentityUnknown = errors.New("iden3/go-jwz: genesis state of the identity is not found")
+)
+
+// IdentityPolicy defines which of genesis and profile identities are accepted as the sender
+type IdentityPolicy int
+
+const (
+	// IdentityAny accepts both genesis and profile identities
+	IdentityAny IdentityPolicy = iota
+	// IdentityGenesisOnly accepts only genesis identities
+	IdentityGenesisOnly
+	// IdentityProfileOnly accepts only profile identities, derived from genesis identity with profile nonce
+	IdentityProfileOnly
+)
+
+// GenesisStateResolver returns genesis (first) state of identity, e.g. from the state contract history.
+// For profile identity it returns genesis state of the identity the profile is derived from.
+// Returns nil state if identity is unknown.
+type GenesisStateResolver interface {
+	GenesisState(ctx context.Context, id core.ID) (*big.Int, error)
+}
+
+// DIDResolver converts identity proven by the token to DID
+type DIDResolver func(id core.ID) (*w3c.DID, error)
+
+// defaultDIDResolver resolves DID method, blockchain and network from the type of the identity
+func defaultDIDResolver(id core.ID) (*w3c.DID, error) {
+	return core.ParseDIDFromID(id)
+}
+
+// WithDIDResolver sets resolver that converts identity proven by the token to DID of the sender,
+// by default DID method, blockchain and network are resolved from the type of the identity.
+func WithDIDResolver(resolver DIDResolver) VerifierOption {
+	return func(v *Verifier) {
+		v.didResolver = resolver
+	}
+}
+
+// WithIdentityPolicy sets which of genesis and profile identities are accepted as the sender.
+// Token of identity which genesis state isn't found by the resolver is rejected with ErrIdentityUnknown.
+func WithIdentityPolicy(policy IdentityPolicy, resolver GenesisStateResolver) VerifierOption {
+	return func(v *Verifier) {
+		v.identityPolicy = policy
+		v.genesisStateResolver = resolver
+	}
+}
+
+// SenderID returns identity proven by the token
+func (r *VerificationResult) SenderID() (*core.ID, error) {
+	return senderID(r.Token)
+}
+
+// SenderDID returns DID of identity proven by the token
+func (r *VerificationResult) SenderDID() (*w3c.DID, error) {
+	id, err := r.SenderID()
+	if err != nil {
+		return nil, err
+	}
+	resolver := r.didResolver
+	if resolver == nil {
+		resolver = defaultDIDResolver
+	}
+	return resolver(*id)
+}
+
+// senderCircuits are circuits that prove the sender of the token with user id public signal
+var senderCircuits = map[circuits.CircuitID]bool{
+	circuits.AuthCircuitID:   true,
+	circuits.AuthV2CircuitID: true,
+}
+
+// senderID returns user id public signal of auth circuits
+func senderID(token *Token) (*core.ID, error) {
+	if !senderCircuits[circuits.CircuitID(token.CircuitID)] {
+		return nil, fmt.Errorf("%w: %s", ErrSenderNotProven, token.CircuitID)
+	}
+	pubSignals, err := token.PubSignals()
+	if err != nil {
+		return nil, err
+	}
+	var id *core.ID
+	switch outs := pubSignals.(type) {
+	case *circuits.AuthV2PubSignals:
+		id = outs.UserID
+	case *circuits.AuthPubSignals:
+		id = outs.UserID
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrSenderNotProven, token.CircuitID)
+	}
+	if id == nil {
+		return nil, errors.New("iden3/go-jwz: missing sender identity")
+	}
+	return id, nil
+}
+
+// checkFrom checks that 'from' claim, if it's set, is DID of the proven identity resolved with DIDResolver.
+// Token with 'from' claim is rejected with ErrSenderNotProven if its circuit doesn't prove the sender.
+func (r *VerificationResult) checkFrom() error {
+	from, ok := r.Claims["from"]
+	if !ok {
+		return nil
+	}
+	fromStr, ok := from.(string)
+	if !ok {
+		return errors.New("iden3/go-jwz: claim from must be a string")
+	}
+	fromDID, err := w3c.ParseDID(fromStr)
+	if err != nil {
+		return fmt.Errorf("iden3/go-jwz: invalid from claim: %w", err)
+	}
+	did, err := r.SenderDID()
+	if err != nil {
+		return err
+	}
+	if fromDID.String() != did.String() {
+		return ErrSenderMismatch
+	}
+	return nil
+}
+
+// IsProfile returns true if identity proven by the token is not genesis one, i.e. it's a profile derived
+// from genesis identity with profile nonce. Identity is genesis if its state is genesis state of the identity.
+// Returns ErrIdentityUnknown if resolver doesn't find genesis state of the identity.
+func (r *VerificationResult) IsProfile(ctx context.Context, resolver GenesisStateResolver) (bool, error) {
+	id, err := r.SenderID()
+	if err != nil {
+		return false, err
+	}
+	state, err := resolver.GenesisState(ctx, *id)
+	if err != nil {
+		return false, err
+	}
+	if state == nil {
+		return false, fmt.Errorf("%w: %s", ErrIdentityUnknown, id)
+	}
+	isGenesis, err := core.CheckGenesisStateID(id.BigInt(), state)
+	if err != nil {
+		return false, err
+	}
+	return !isGenesis, nil
+}
+
+// IsProfileOf returns true if identity proven by the token is profile of genesis identity with the nonce.
+// Nonce must be positive, as zero nonce derives the genesis identity itself.
+func (r *VerificationResult) IsProfileOf(genesisID core.ID, nonce *big.Int) (bool, error) {
+	if nonce == nil || nonce.Sign() <= 0 {
+		return false, errors.New("iden3/go-jwz: profile nonce must be positive")
+	}
+	id, err := r.SenderID()
+	if err != nil {
+		return false, err
+	}
+	profileID, err := core.ProfileID(genesisID, nonce)
+	if err != nil {
+		return false, err
+	}
+	return profileID.Equal(id), nil
+}
+
+// checkIdentityPolicy checks that identity proven by the token is accepted by the policy
+func (r *VerificationResult) checkIdentityPolicy(ctx context.Context, policy IdentityPolicy,
+	resolver GenesisStateResolver) error {
+
+	if policy == IdentityAny {
+		return nil
+	}
+	if resolver == nil {
+		return errors.New("iden3/go-jwz: genesis state resolver is not set")
+	}
+	isProfile, err := r.IsProfile(ctx, resolver)
+	if err != nil {
+		return err
+	}
+	switch {
+	case policy == IdentityGenesisOnly && isProfile:
+		return ErrProfileNotAllowed
+	case policy == IdentityProfileOnly && !isProfile:
+		return ErrGenesisNotAllowed
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package jwz
+
+import (
+	"context"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/iden3/go-circuits/v2"
+	core "github.com/iden3/go-iden3-core/v2"
+	"github.com/iden3/go-iden3-core/v2/w3c"
+	"github.com/stretchr/testify/assert"
+)
+
+const authV2Sender = "did:iden3:polygon:mumbai:x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29"
+
+func TestVerificationResult_Sender(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+	keys := KeyStoreMap{AuthV2Groth16Alg: verificationKey}
+
+	res, err := NewVerifier(WithKeyStore(keys)).Verify(context.Background(), authV2Token)
+	assert.NoError(t, err)
+
+	id, err := res.SenderID()
+	assert.NoError(t, err)
+	assert.Equal(t, "x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29", id.String())
+
+	did, err := res.SenderDID()
+	assert.NoError(t, err)
+	assert.Equal(t, authV2Sender, did.String())
+
+	// custom DID method resolution
+	resolver := func(id core.ID) (*w3c.DID, error) {
+		return w3c.ParseDID("did:example:" + id.String())
+	}
+	res, err = NewVerifier(WithKeyStore(keys), WithDIDResolver(resolver)).Verify(context.Background(), authV2Token)
+	assert.NoError(t, err)
+	did, err = res.SenderDID()
+	assert.NoError(t, err)
+	assert.Equal(t, "did:example:x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29", did.String())
+
+	// token of circuit without sender identity
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	_, err = (&VerificationResult{Token: token}).SenderID()
+	assert.ErrorIs(t, err, ErrSenderNotProven)
+
+	// user id of query circuit is not the sender of the token
+	token.CircuitID = string(circuits.AtomicQueryMTPV2CircuitID)
+	_, err = (&VerificationResult{Token: token}).SenderID()
+	assert.ErrorIs(t, err, ErrSenderNotProven)
+}
+
+func TestVerificationResult_CheckFrom(t *testing.T) {
+	token, err := Parse(authV2Token)
+	assert.NoError(t, err)
+
+	mockToken, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	_, err = mockToken.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	exampleResolver := func(id core.ID) (*w3c.DID, error) {
+		return w3c.ParseDID("did:example:" + id.String())
+	}
+
+	tests := []struct {
+		name     string
+		token    *Token
+		resolver DIDResolver
+		claims   map[string]interface{}
+		err      string
+	}{
+		{name: "no from claim", claims: map[string]interface{}{}},
+		{name: "from proven identity", claims: map[string]interface{}{"from": authV2Sender}},
+		{
+			name:     "from proven identity with custom resolver",
+			resolver: exampleResolver,
+			claims:   map[string]interface{}{"from": "did:example:x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29"},
+		},
+		{
+			name:     "from default DID with custom resolver",
+			resolver: exampleResolver,
+			claims:   map[string]interface{}{"from": authV2Sender},
+			err:      ErrSenderMismatch.Error(),
+		},
+		{
+			name:   "from other identity",
+			claims: map[string]interface{}{"from": "did:iden3:polygon:mumbai:x3HstHLj2rTp6HHXk2WczYP7w3rpCsRbwCMeaQ2H2"},
+			err:    ErrSenderMismatch.Error(),
+		},
+		{
+			name:   "from is not a string",
+			claims: map[string]interface{}{"from": 1.0},
+			err:    "iden3/go-jwz: claim from must be a string",
+		},
+		{
+			name:   "circuit doesn't prove sender",
+			token:  mockToken,
+			claims: map[string]interface{}{"from": authV2Sender},
+			err:    "iden3/go-jwz: sender identity is not proven by the circuit: mockCircuit",
+		},
+		{name: "no from claim with circuit without sender", token: mockToken, claims: map[string]interface{}{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := &VerificationResult{Token: token, Claims: tt.claims, didResolver: tt.resolver}
+			if tt.token != nil {
+				res.Token = tt.token
+			}
+			err := res.checkFrom()
+			if tt.err == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tt.err)
+			}
+		})
+	}
+}
+
+// authV2GenesisState is genesis state of the identity proven by authV2Token
+const authV2GenesisState = "13749793311041076104545663747883540987785640262360452307923674522221753800226"
+
+type mockGenesisStateResolver map[core.ID]*big.Int
+
+func (r mockGenesisStateResolver) GenesisState(_ context.Context, id core.ID) (*big.Int, error) {
+	return r[id], nil
+}
+
+func TestVerificationResult_Profile(t *testing.T) {
+	verificationKey, err := os.ReadFile("./testdata/authV2/verification_key.json")
+	assert.NoError(t, err)
+	keys := KeyStoreMap{AuthV2Groth16Alg: verificationKey}
+
+	senderID, err := core.IDFromString("x4jcHP4XHTK3vX58AHZPyHE8kYjneyE6FZRfz7K29")
+	assert.NoError(t, err)
+	genesisState, _ := new(big.Int).SetString(authV2GenesisState, 10)
+
+	known := mockGenesisStateResolver{senderID: genesisState}
+	unknown := mockGenesisStateResolver{}
+	// state of other identity, as returned for profile identity
+	profile := mockGenesisStateResolver{senderID: big.NewInt(1)}
+
+	res, err := NewVerifier(WithKeyStore(keys)).Verify(context.Background(), authV2Token)
+	assert.NoError(t, err)
+
+	isProfile, err := res.IsProfile(context.Background(), known)
+	assert.NoError(t, err)
+	assert.False(t, isProfile)
+	_, err = res.IsProfile(context.Background(), unknown)
+	assert.ErrorIs(t, err, ErrIdentityUnknown)
+	isProfile, err = res.IsProfile(context.Background(), profile)
+	assert.NoError(t, err)
+	assert.True(t, isProfile)
+
+	// zero nonce derives the genesis identity itself
+	_, err = res.IsProfileOf(senderID, big.NewInt(0))
+	assert.EqualError(t, err, "iden3/go-jwz: profile nonce must be positive")
+	isProfileOf, err := res.IsProfileOf(senderID, big.NewInt(1))
+	assert.NoError(t, err)
+	assert.False(t, isProfileOf)
+
+	tests := []struct {
+		name     string
+		policy   IdentityPolicy
+		resolver GenesisStateResolver
+		err      error
+	}{
+		{name: "any", policy: IdentityAny},
+		{name: "genesis only", policy: IdentityGenesisOnly, resolver: known},
+		{name: "genesis only, profile", policy: IdentityGenesisOnly, resolver: profile, err: ErrProfileNotAllowed},
+		{name: "genesis only, unknown", policy: IdentityGenesisOnly, resolver: unknown, err: ErrIdentityUnknown},
+		{name: "profile only", policy: IdentityProfileOnly, resolver: profile},
+		{name: "profile only, genesis", policy: IdentityProfileOnly, resolver: known, err: ErrGenesisNotAllowed},
+		{name: "profile only, unknown", policy: IdentityProfileOnly, resolver: unknown, err: ErrIdentityUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(WithKeyStore(keys), WithIdentityPolicy(tt.policy, tt.resolver)).
+				Verify(context.Background(), authV2Token)
+			assert.ErrorIs(t, err, tt.err)
+		})
+	}
+
+	_, err = NewVerifier(WithKeyStore(keys), WithIdentityPolicy(IdentityGenesisOnly, nil)).
+		Verify(context.Background(), authV2Token)
+	assert.EqualError(t, err, "iden3/go-jwz: genesis state resolver is not set")
+}
@@ -0,0 +1,312 @@
+package jwz
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/iden3/go-iden3-crypto/utils"
+)
+
+const (
+	// SignatureAlgES256 is ECDSA P-256 with SHA-256 signature of JWS signing input
+	SignatureAlgES256 = "ES256"
+	// SignatureAlgEdDSA is Ed25519 signature of JWS signing input
+	SignatureAlgEdDSA = "EdDSA"
+	// SignatureAlgBJJ is BabyJubJub EdDSA-Poseidon signature of the message hash,
+	// it's cheap to verify inside of the circuit.
+	SignatureAlgBJJ = "BJJ"
+)
+
+// Signer creates conventional signature of hybrid token.
+type Signer interface {
+	Alg() string                         // Returns 'sigAlg' header value
+	Sign(message []byte) ([]byte, error) // Returns signature of the message
+}
+
+// SignatureVerifier verifies conventional signature of hybrid token.
+type SignatureVerifier interface {
+	Alg() string                            // Returns 'sigAlg' header value
+	Verify(message, signature []byte) error // Returns nil if signature is valid
+}
+
+// HybridPolicy defines which of the proof and the signature of hybrid token must be valid.
+type HybridPolicy int
+
+const (
+	// HybridRequireBoth requires both the proof and the signature to be valid
+	HybridRequireBoth HybridPolicy = iota
+	// HybridRequireProof requires the proof to be valid, signature is not checked
+	HybridRequireProof
+	// HybridRequireSignature requires the signature to be valid, proof is not checked
+	HybridRequireSignature
+	// HybridRequireEither requires the proof or the signature to be valid
+	HybridRequireEither
+)
+
+// WithSigner makes token hybrid: besides zkp, protected header and payload are signed with conventional signature.
+// Signature is created by Prove and is present only in full serialization.
+func (token *Token) WithSigner(signer Signer) error {
+	if token.sealed {
+		return errTokenSealed
+	}
+	if _, ok := token.raw.Header[headerSigAlg]; ok {
+		return fmt.Errorf("iden3/go-jwz: header %s is already set as unprotected", headerSigAlg)
+	}
+	token.header[headerSigAlg] = signer.Alg()
+	token.signer = signer
+	return nil
+}
+
+// IsHybrid returns true if token carries conventional signature along with zkp
+func (token *Token) IsHybrid() bool {
+	_, ok := token.header[headerSigAlg]
+	return ok
+}
+
+// sign creates signature of the token with the signer
+func (token *Token) sign(msgHash []byte) error {
+	message, err := token.signatureMessage(token.signer.Alg(), msgHash)
+	if err != nil {
+		return err
+	}
+	token.raw.Signature, err = token.signer.Sign(message)
+	return err
+}
+
+// signatureMessage returns what is signed by the alg: JWS signing input or message hash for BJJ
+func (token *Token) signatureMessage(alg string, msgHash []byte) ([]byte, error) {
+	if alg == SignatureAlgBJJ {
+		return msgHash, nil
+	}
+	if token.IsDetached() && token.raw.Payload == nil {
+		return nil, errors.New("iden3/go-jwz: detached payload is not attached")
+	}
+	payload := string(token.raw.Payload)
+	if token.IsPayloadEncoded() {
+		payload = base64.RawURLEncoding.EncodeToString(token.raw.Payload)
+	}
+	return []byte(base64.RawURLEncoding.EncodeToString(token.raw.Protected) + "." + payload), nil
+}
+
+// VerifySignature verifies conventional signature of hybrid token.
+func (token *Token) VerifySignature(verifier SignatureVerifier) error {
+	alg, _ := token.header[headerSigAlg].(string)
+	if alg == "" {
+		return errors.New("iden3/go-jwz: token is not signed")
+	}
+	if alg != verifier.Alg() {
+		return fmt.Errorf("iden3/go-jwz: signature alg %s doesn't match verifier alg %s", alg, verifier.Alg())
+	}
+	if len(token.raw.Signature) == 0 {
+		return errors.New("iden3/go-jwz: missing signature")
+	}
+	if err := token.validateCritical(); err != nil {
+		return err
+	}
+
+	var msgHash []byte
+	if alg == SignatureAlgBJJ {
+		var err error
+		msgHash, err = token.GetMessageHash()
+		if err != nil {
+			return err
+		}
+	}
+	message, err := token.signatureMessage(alg, msgHash)
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(message, token.raw.Signature)
+}
+
+// VerifyHybrid verifies the proof and the signature of hybrid token according to the policy.
+func (token *Token) VerifyHybrid(policy HybridPolicy, verificationKey []byte, verifier SignatureVerifier) error {
+	verifyProof := func() error {
+		_, err := token.Verify(verificationKey)
+		return err
+	}
+	verifySignature := func() error {
+		if verifier == nil {
+			return errors.New("iden3/go-jwz: signature verifier is not provided")
+		}
+		return token.VerifySignature(verifier)
+	}
+
+	switch policy {
+	case HybridRequireBoth:
+		if err := verifyProof(); err != nil {
+			return err
+		}
+		return verifySignature()
+	case HybridRequireProof:
+		return verifyProof()
+	case HybridRequireSignature:
+		return verifySignature()
+	case HybridRequireEither:
+		proofErr := verifyProof()
+		if proofErr == nil {
+			return nil
+		}
+		if err := verifySignature(); err != nil {
+			return fmt.Errorf("iden3/go-jwz: neither proof (%v) nor signature (%w) is valid", proofErr, err)
+		}
+		return nil
+	default:
+		return fmt.Errorf("iden3/go-jwz: unknown hybrid policy %d", policy)
+	}
+}
+
+type es256Signer struct {
+	key *ecdsa.PrivateKey
+}
+
+// NewES256Signer returns ES256 signer with P-256 private key
+func NewES256Signer(key *ecdsa.PrivateKey) Signer {
+	return &es256Signer{key: key}
+}
+
+func (s *es256Signer) Alg() string {
+	return SignatureAlgES256
+}
+
+// Sign returns 64 bytes R || S signature of sha256 hash of the message
+func (s *es256Signer) Sign(message []byte) ([]byte, error) {
+	if s.key.Curve != elliptic.P256() {
+		return nil, errors.New("iden3/go-jwz: ES256 requires P-256 key")
+	}
+	digest := sha256.Sum256(message)
+	r, sig, err := ecdsa.Sign(rand.Reader, s.key, digest[:])
+	if err != nil {
+		return nil, err
+	}
+	out := make([]byte, 64)
+	r.FillBytes(out[:32])
+	sig.FillBytes(out[32:])
+	return out, nil
+}
+
+type es256Verifier struct {
+	key *ecdsa.PublicKey
+}
+
+// NewES256Verifier returns ES256 verifier with P-256 public key
+func NewES256Verifier(key *ecdsa.PublicKey) SignatureVerifier {
+	return &es256Verifier{key: key}
+}
+
+func (v *es256Verifier) Alg() string {
+	return SignatureAlgES256
+}
+
+func (v *es256Verifier) Verify(message, signature []byte) error {
+	if len(signature) != 64 {
+		return errors.New("iden3/go-jwz: invalid ES256 signature length")
+	}
+	digest := sha256.Sum256(message)
+	r := new(big.Int).SetBytes(signature[:32])
+	s := new(big.Int).SetBytes(signature[32:])
+	if !ecdsa.Verify(v.key, digest[:], r, s) {
+		return errors.New("iden3/go-jwz: invalid ES256 signature")
+	}
+	return nil
+}
+
+type eddsaSigner struct {
+	key ed25519.PrivateKey
+}
+
+// NewEdDSASigner returns EdDSA signer with Ed25519 private key
+func NewEdDSASigner(key ed25519.PrivateKey) Signer {
+	return &eddsaSigner{key: key}
+}
+
+func (s *eddsaSigner) Alg() string {
+	return SignatureAlgEdDSA
+}
+
+func (s *eddsaSigner) Sign(message []byte) ([]byte, error) {
+	if len(s.key) != ed25519.PrivateKeySize {
+		return nil, errors.New("iden3/go-jwz: invalid Ed25519 private key")
+	}
+	return ed25519.Sign(s.key, message), nil
+}
+
+type eddsaVerifier struct {
+	key ed25519.PublicKey
+}
+
+// NewEdDSAVerifier returns EdDSA verifier with Ed25519 public key
+func NewEdDSAVerifier(key ed25519.PublicKey) SignatureVerifier {
+	return &eddsaVerifier{key: key}
+}
+
+func (v *eddsaVerifier) Alg() string {
+	return SignatureAlgEdDSA
+}
+
+func (v *eddsaVerifier) Verify(message, signature []byte) error {
+	if len(v.key) != ed25519.PublicKeySize || !ed25519.Verify(v.key, message, signature) {
+		return errors.New("iden3/go-jwz: invalid EdDSA signature")
+	}
+	return nil
+}
+
+type bjjSigner struct {
+	key *babyjub.PrivateKey
+}
+
+// NewBJJSigner returns BabyJubJub signer
+func NewBJJSigner(key *babyjub.PrivateKey) Signer {
+	return &bjjSigner{key: key}
+}
+
+func (s *bjjSigner) Alg() string {
+	return SignatureAlgBJJ
+}
+
+// Sign returns compressed EdDSA-Poseidon signature of the message hash
+func (s *bjjSigner) Sign(msgHash []byte) ([]byte, error) {
+	m := new(big.Int).SetBytes(msgHash)
+	if !utils.CheckBigIntInField(m) {
+		return nil, errors.New("iden3/go-jwz: message hash is not in the field")
+	}
+	sig := s.key.SignPoseidon(m).Compress()
+	return sig[:], nil
+}
+
+type bjjVerifier struct {
+	key *babyjub.PublicKey
+}
+
+// NewBJJVerifier returns BabyJubJub signature verifier
+func NewBJJVerifier(key *babyjub.PublicKey) SignatureVerifier {
+	return &bjjVerifier{key: key}
+}
+
+func (v *bjjVerifier) Alg() string {
+	return SignatureAlgBJJ
+}
+
+func (v *bjjVerifier) Verify(msgHash, signature []byte) error {
+	var sigComp babyjub.SignatureComp
+	if len(signature) != len(sigComp) {
+		return errors.New("iden3/go-jwz: invalid BJJ signature length")
+	}
+	copy(sigComp[:], signature)
+	sig, err := sigComp.Decompress()
+	if err != nil {
+		return err
+	}
+	if !v.key.VerifyPoseidon(new(big.Int).SetBytes(msgHash), sig) {
+		return errors.New("iden3/go-jwz: invalid BJJ signature")
+	}
+	return nil
+}
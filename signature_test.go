@@ -0,0 +1,141 @@
+package jwz
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"testing"
+
+	"github.com/iden3/go-iden3-crypto/babyjub"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToken_Hybrid(t *testing.T) {
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	otherECKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	otherEdPub, _, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	bjjKey := babyjub.NewRandPrivKey()
+	otherBJJKey := babyjub.NewRandPrivKey()
+
+	tests := []struct {
+		name          string
+		signer        Signer
+		verifier      SignatureVerifier
+		wrongVerifier SignatureVerifier
+	}{
+		{
+			name:          SignatureAlgES256,
+			signer:        NewES256Signer(ecKey),
+			verifier:      NewES256Verifier(&ecKey.PublicKey),
+			wrongVerifier: NewES256Verifier(&otherECKey.PublicKey),
+		},
+		{
+			name:          SignatureAlgEdDSA,
+			signer:        NewEdDSASigner(edKey),
+			verifier:      NewEdDSAVerifier(edPub),
+			wrongVerifier: NewEdDSAVerifier(otherEdPub),
+		},
+		{
+			name:          SignatureAlgBJJ,
+			signer:        NewBJJSigner(&bjjKey),
+			verifier:      NewBJJVerifier(bjjKey.Public()),
+			wrongVerifier: NewBJJVerifier(otherBJJKey.Public()),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+			assert.NoError(t, err)
+			assert.NoError(t, token.WithSigner(tt.signer))
+			assert.True(t, token.IsHybrid())
+
+			tokenString, err := token.Prove(nil, nil)
+			assert.NoError(t, err)
+
+			fullString, err := token.FullSerialize()
+			assert.NoError(t, err)
+			assert.Contains(t, fullString, `"signature"`)
+			// hybrid token is returned in full serialization, as compact one loses signature
+			assert.Equal(t, fullString, tokenString)
+			_, err = token.CompactSerialize()
+			assert.Error(t, err)
+
+			_, err = FullToCompact(fullString)
+			assert.Error(t, err)
+
+			parsed, err := Parse(fullString)
+			assert.NoError(t, err)
+			assert.True(t, parsed.IsHybrid())
+			assert.Equal(t, tt.signer.Alg(), parsed.GetHeader()[headerSigAlg])
+
+			assert.NoError(t, parsed.VerifySignature(tt.verifier))
+			assert.NoError(t, parsed.VerifyHybrid(HybridRequireBoth, nil, tt.verifier))
+			assert.NoError(t, parsed.VerifyHybrid(HybridRequireSignature, nil, tt.verifier))
+			assert.NoError(t, parsed.VerifyHybrid(HybridRequireProof, nil, nil))
+			assert.NoError(t, parsed.VerifyHybrid(HybridRequireEither, nil, tt.wrongVerifier))
+
+			assert.Error(t, parsed.VerifySignature(tt.wrongVerifier))
+			assert.Error(t, parsed.VerifyHybrid(HybridRequireBoth, nil, tt.wrongVerifier))
+			assert.Error(t, parsed.VerifyHybrid(HybridRequireSignature, nil, nil))
+		})
+	}
+}
+
+func TestToken_HybridTampered(t *testing.T) {
+	edPub, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+
+	token, err := NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithSigner(NewEdDSASigner(edKey)))
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+
+	token.raw.Payload = []byte("other message")
+	assert.Error(t, token.VerifySignature(NewEdDSAVerifier(edPub)))
+	// neither the proof nor the signature matches the payload
+	assert.Error(t, token.VerifyHybrid(HybridRequireEither, nil, NewEdDSAVerifier(edPub)))
+
+	// token without signer is not hybrid
+	token, err = NewWithPayload(mockProvingMethodInstance, []byte("mymessage"), mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.False(t, token.IsHybrid())
+	_, err = token.Prove(nil, nil)
+	assert.NoError(t, err)
+	assert.Error(t, token.VerifySignature(NewEdDSAVerifier(edPub)))
+}
+
+func TestToken_HybridPayloadReader(t *testing.T) {
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	bjjKey := babyjub.NewRandPrivKey()
+	payload := []byte("large document")
+
+	// EdDSA signs the payload, so it can't be streamed
+	token, err := NewWithDetachedPayload(mockProvingMethodInstance, nil, mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithSigner(NewEdDSASigner(edKey)))
+	_, err = token.ProveWithPayloadReader(bytes.NewReader(payload), nil, nil)
+	assert.EqualError(t, err, "iden3/go-jwz: EdDSA signature requires attached payload, use Prove")
+
+	// BJJ signs the message hash
+	token, err = NewWithDetachedPayload(mockProvingMethodInstance, nil, mockPrepareInputs)
+	assert.NoError(t, err)
+	assert.NoError(t, token.WithSigner(NewBJJSigner(&bjjKey)))
+	tokenString, err := token.ProveWithPayloadReader(bytes.NewReader(payload), nil, nil)
+	assert.NoError(t, err)
+
+	parsed, err := ParseWithDetachedPayload(tokenString, payload)
+	assert.NoError(t, err)
+	assert.NoError(t, parsed.VerifyHybrid(HybridRequireBoth, nil, NewBJJVerifier(bjjKey.Public())))
+}
@@ -0,0 +1,356 @@
+package jwz
+
+import (
+	"container/heap"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrAlgNotAllowed is returned when proving method of the token is not allowed by the verifier
+	ErrAlgNotAllowed = errors.New("iden3/go-jwz: proving method is not allowed")
+	// ErrTokenExpired is returned when 'exp' claim of the token is in the past
+	ErrTokenExpired = errors.New("iden3/go-jwz: token is expired")
+	// ErrTokenNotYetValid is returned when 'nbf' claim of the token is in the future
+	ErrTokenNotYetValid = errors.New("iden3/go-jwz: token is not valid yet")
+	// ErrMethodDisabled is returned when proving method of the token is disabled in the registry
+	ErrMethodDisabled = errors.New("iden3/go-jwz: proving method is disabled")
+	// ErrTokenReplayed is returned when the token was already verified
+	ErrTokenReplayed = errors.New("iden3/go-jwz: token is replayed")
+)
+
+// defaultReplayTTL is how long token is remembered by replay cache if it has no 'exp' claim
+const defaultReplayTTL = time.Hour
+
+// KeyStore provides verification keys of proving methods
+type KeyStore interface {
+	VerificationKey(ctx context.Context, alg ProvingMethodAlg) ([]byte, error)
+}
+
+// KeyStoreMap is in-memory KeyStore
+type KeyStoreMap map[ProvingMethodAlg][]byte
+
+// VerificationKey returns verification key of the proving method
+func (m KeyStoreMap) VerificationKey(_ context.Context, alg ProvingMethodAlg) ([]byte, error) {
+	key, ok := m[alg]
+	if !ok {
+		return nil, fmt.Errorf("iden3/go-jwz: verification key for %s %s is not found", alg.Alg, alg.CircuitID)
+	}
+	return key, nil
+}
+
+// StateResolver checks that the states proven by the token (e.g. GIST root) are published and not outdated
+type StateResolver interface {
+	Resolve(ctx context.Context, token *Token) error
+}
+
+// ReplayCache remembers verified tokens to detect their replay.
+// Token id is derived from message hash and public signals of the proof.
+type ReplayCache interface {
+	// CheckAndStore stores token id until expiresAt and returns true if it's already stored
+	CheckAndStore(ctx context.Context, id string, expiresAt time.Time) (bool, error)
+}
+
+// VerifierOption configures Verifier
+type VerifierOption func(v *Verifier)
+
+// WithAllowedAlgs allows only the listed proving methods, all methods of the registry are allowed by default
+func WithAllowedAlgs(algs ...ProvingMethodAlg) VerifierOption {
+	return func(v *Verifier) {
+		for _, alg := range algs {
+			v.allowedAlgs[alg] = true
+		}
+	}
+}
+
+// WithKeyStore sets store of verification keys
+func WithKeyStore(keys KeyStore) VerifierOption {
+	return func(v *Verifier) {
+		v.keys = keys
+	}
+}
+
+// WithExpectedType requires 'typ' header of the token to be equal to typ
+func WithExpectedType(typ string) VerifierOption {
+	return func(v *Verifier) {
+		v.typ = typ
+	}
+}
+
+// WithClock sets clock that is used to check 'exp' and 'nbf' claims
+func WithClock(clock func() time.Time) VerifierOption {
+	return func(v *Verifier) {
+		v.clock = clock
+	}
+}
+
+// WithStateResolver sets resolver that checks states proven by the token
+func WithStateResolver(resolver StateResolver) VerifierOption {
+	return func(v *Verifier) {
+		v.stateResolver = resolver
+	}
+}
+
+// WithReplayCache rejects tokens that were already verified. Token is remembered until its 'exp' claim
+// or for ttl if it has no 'exp' claim.
+func WithReplayCache(cache ReplayCache, ttl time.Duration) VerifierOption {
+	return func(v *Verifier) {
+		v.replayCache = cache
+		v.replayTTL = ttl
+	}
+}
+
+// WithParseOptions sets options that tokens are parsed with, so registry of proving methods and
+// disabled methods policy are configured with the same WithRegistry and WithDisabledMethodsAllowed as for Parse
+func WithParseOptions(opts ...ParseOption) VerifierOption {
+	return func(v *Verifier) {
+		v.parseOpts = append(v.parseOpts, opts...)
+	}
+}
+
+// Verifier parses and verifies tokens according to the policy it's configured with.
+// Verifier is safe for concurrent use.
+type Verifier struct {
+	allowedAlgs   map[ProvingMethodAlg]bool
+	parseOpts     []ParseOption
+	keys          KeyStore
+	typ           string
+	clock         func() time.Time
+	stateResolver StateResolver
+	replayCache   ReplayCache
+	replayTTL     time.Duration
+	didResolver   DIDResolver
+
+	identityPolicy       IdentityPolicy
+	genesisStateResolver GenesisStateResolver
+}
+
+// NewVerifier creates a new Verifier
+func NewVerifier(opts ...VerifierOption) *Verifier {
+	v := &Verifier{
+		allowedAlgs: map[ProvingMethodAlg]bool{},
+		clock:       time.Now,
+		replayTTL:   defaultReplayTTL,
+		didResolver: defaultDIDResolver,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// VerificationResult is the result of successful token verification
+type VerificationResult struct {
+	Token       *Token
+	Alg         ProvingMethodAlg
+	Type        string
+	MessageHash []byte
+	// Claims is JSON object payload of the token, nil if payload is not a JSON object
+	Claims map[string]interface{}
+	// ExpiresAt is the time of 'exp' claim, zero if claim is not set
+	ExpiresAt time.Time
+	// Warnings are non fatal issues, e.g. deprecated proving method
+	Warnings []string
+
+	didResolver DIDResolver
+}
+
+// Verify parses and verifies the token in compact or full serialization format
+func (v *Verifier) Verify(ctx context.Context, tokenString string) (*VerificationResult, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if v.keys == nil {
+		return nil, errors.New("iden3/go-jwz: verifier key store is not set")
+	}
+
+	token, err := Parse(tokenString, v.parseOpts...)
+	if err != nil {
+		return nil, err
+	}
+	res := &VerificationResult{
+		Token: token,
+		Alg:   NewProvingMethodAlg(token.Alg, token.CircuitID),
+		Type:  token.GetType(),
+
+		didResolver: v.didResolver,
+	}
+
+	if len(v.allowedAlgs) != 0 && !v.allowedAlgs[res.Alg] {
+		return nil, fmt.Errorf("%w: %s %s", ErrAlgNotAllowed, res.Alg.Alg, res.Alg.CircuitID)
+	}
+	if err = token.checkMethod(); err != nil {
+		return nil, err
+	}
+	res.Warnings = token.Warnings()
+	if v.typ != "" && res.Type != v.typ {
+		return nil, fmt.Errorf("iden3/go-jwz: token type %s is not %s", res.Type, v.typ)
+	}
+
+	err = v.verifyClaims(token, res)
+	if err != nil {
+		return nil, err
+	}
+
+	verificationKey, err := v.keys.VerificationKey(ctx, res.Alg)
+	if err != nil {
+		return nil, err
+	}
+	res.MessageHash, err = token.attachedMessageHash()
+	if err != nil {
+		return nil, err
+	}
+	_, err = token.verify(res.MessageHash, verificationKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = res.checkFrom()
+	if err != nil {
+		return nil, err
+	}
+	err = res.checkIdentityPolicy(ctx, v.identityPolicy, v.genesisStateResolver)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.stateResolver != nil {
+		err = v.stateResolver.Resolve(ctx, token)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if v.replayCache != nil {
+		expiresAt := res.ExpiresAt
+		if expiresAt.IsZero() {
+			expiresAt = v.clock().Add(v.replayTTL)
+		}
+		seen, err := v.replayCache.CheckAndStore(ctx, replayKey(res), expiresAt)
+		if err != nil {
+			return nil, err
+		}
+		if seen {
+			return nil, ErrTokenReplayed
+		}
+	}
+
+	return res, nil
+}
+
+// replayKey identifies the token by message hash and public signals, so tokens of different senders
+// with the same headers and payload don't collide. The proof itself isn't used, as groth16 proof
+// can be re-randomized by anyone without changing public signals.
+func replayKey(res *VerificationResult) string {
+	h := sha256.New()
+	h.Write(res.MessageHash)
+	if res.Token.ZkProof != nil {
+		for _, s := range res.Token.ZkProof.PubSignals {
+			h.Write([]byte{0})
+			h.Write([]byte(s))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// verifyClaims checks 'exp' and 'nbf' claims of JSON object payload
+func (v *Verifier) verifyClaims(token *Token, res *VerificationResult) error {
+	var claims map[string]interface{}
+	if json.Unmarshal(token.GetPayload(), &claims) != nil {
+		return nil
+	}
+	res.Claims = claims
+
+	now := v.clock()
+	if exp, ok := claims["exp"]; ok {
+		t, err := numericDate("exp", exp)
+		if err != nil {
+			return err
+		}
+		if !now.Before(t) {
+			return ErrTokenExpired
+		}
+		res.ExpiresAt = t
+	}
+	if nbf, ok := claims["nbf"]; ok {
+		t, err := numericDate("nbf", nbf)
+		if err != nil {
+			return err
+		}
+		if now.Before(t) {
+			return ErrTokenNotYetValid
+		}
+	}
+	return nil
+}
+
+// numericDate converts NumericDate claim (seconds since epoch) to time
+func numericDate(name string, v interface{}) (time.Time, error) {
+	seconds, ok := v.(float64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("iden3/go-jwz: claim %s must be a number", name)
+	}
+	return time.Unix(int64(seconds), 0), nil
+}
+
+// memoryReplayCache is ReplayCache that keeps token ids in memory
+type memoryReplayCache struct {
+	mu    sync.Mutex
+	clock func() time.Time
+	ids   map[string]time.Time
+	queue replayQueue
+}
+
+// NewMemoryReplayCache returns ReplayCache that keeps token ids in memory of the process
+func NewMemoryReplayCache(clock func() time.Time) ReplayCache {
+	if clock == nil {
+		clock = time.Now
+	}
+	return &memoryReplayCache{clock: clock, ids: map[string]time.Time{}}
+}
+
+func (c *memoryReplayCache) CheckAndStore(_ context.Context, id string, expiresAt time.Time) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// every stored id has the only entry in the queue, so expired ids are removed in order of expiration
+	now := c.clock()
+	for len(c.queue) != 0 && !now.Before(c.queue[0].expiresAt) {
+		delete(c.ids, heap.Pop(&c.queue).(replayEntry).id)
+	}
+	if _, ok := c.ids[id]; ok {
+		return true, nil
+	}
+	c.ids[id] = expiresAt
+	heap.Push(&c.queue, replayEntry{id: id, expiresAt: expiresAt})
+	return false, nil
+}
+
+type replayEntry struct {
+	id        string
+	expiresAt time.Time
+}
+
+// replayQueue is heap of token ids ordered by expiration time
+type replayQueue []replayEntry
+
+func (q replayQueue) Len() int           { return len(q) }
+func (q replayQueue) Less(i, j int) bool { return q[i].expiresAt.Before(q[j].expiresAt) }
+func (q replayQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+
+func (q *replayQueue) Push(x interface{}) {
+	*q = append(*q, x.(replayEntry))
+}
+
+func (q *replayQueue) Pop() interface{} {
+	old := *q
+	e := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return e
+}
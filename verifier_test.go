@@ -0,0 +1,233 @@
+package jwz
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/iden3/go-circuits/v2"
+	"github.com/iden3/go-rapidsnark/types"
+	"github.com/stretchr/testify/assert"
+)
+
+type mockStateResolver struct {
+	err error
+}
+
+func (r *mockStateResolver) Resolve(_ context.Context, _ *Token) error {
+	return r.err
+}
+
+func proveMockClaims(t *testing.T, claims interface{}) string {
+	token, err := NewBuilder().
+		Method(mockProvingMethodInstance).
+		Claims(claims).
+		InputsPreparer(mockPrepareInputs).
+		Prove(context.Background(), ProvingKeys{})
+	assert.NoError(t, err)
+	tokenString, err := token.CompactSerialize()
+	assert.NoError(t, err)
+	return tokenString
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	keys := KeyStoreMap{mockAlg: []byte("{}")}
+
+	tokenString := proveMockClaims(t, map[string]interface{}{"sub": "alice", "exp": now.Unix() + 60})
+
+	v := NewVerifier(
+		WithAllowedAlgs(mockAlg),
+		WithKeyStore(keys),
+		WithExpectedType("JWZ"),
+		WithClock(clock),
+		WithStateResolver(&mockStateResolver{}),
+		WithReplayCache(NewMemoryReplayCache(clock), time.Minute),
+	)
+	res, err := v.Verify(context.Background(), tokenString)
+	assert.NoError(t, err)
+	assert.Equal(t, mockAlg, res.Alg)
+	assert.Equal(t, "JWZ", res.Type)
+	assert.Equal(t, "alice", res.Claims["sub"])
+	assert.Equal(t, now.Add(time.Minute), res.ExpiresAt)
+	assert.NotEmpty(t, res.MessageHash)
+
+	_, err = v.Verify(context.Background(), tokenString)
+	assert.ErrorIs(t, err, ErrTokenReplayed)
+}
+
+func TestVerifier_VerifyRejected(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	clock := func() time.Time { return now }
+	keys := KeyStoreMap{mockAlg: []byte("{}")}
+
+	valid := proveMockClaims(t, map[string]interface{}{"sub": "alice"})
+	authV2Parts := strings.Split(authV2Token, ".")
+
+	tests := []struct {
+		name   string
+		token  string
+		opts   []VerifierOption
+		err    error
+		errMsg string
+	}{
+		{
+			name:   "no key store",
+			token:  valid,
+			errMsg: "iden3/go-jwz: verifier key store is not set",
+		},
+		{
+			name:  "alg is not allowed",
+			token: valid,
+			opts:  []VerifierOption{WithKeyStore(keys), WithAllowedAlgs(AuthV2Groth16Alg)},
+			err:   ErrAlgNotAllowed,
+		},
+		{
+			name:   "unexpected type",
+			token:  valid,
+			opts:   []VerifierOption{WithKeyStore(keys), WithExpectedType("JWZ+auth")},
+			errMsg: "iden3/go-jwz: token type JWZ is not JWZ+auth",
+		},
+		{
+			name:   "no verification key",
+			token:  valid,
+			opts:   []VerifierOption{WithKeyStore(KeyStoreMap{})},
+			errMsg: "iden3/go-jwz: verification key for mock mockCircuit is not found",
+		},
+		{
+			name:  "expired",
+			token: proveMockClaims(t, map[string]interface{}{"exp": now.Unix()}),
+			opts:  []VerifierOption{WithKeyStore(keys), WithClock(clock)},
+			err:   ErrTokenExpired,
+		},
+		{
+			name:  "not valid yet",
+			token: proveMockClaims(t, map[string]interface{}{"nbf": now.Unix() + 1}),
+			opts:  []VerifierOption{WithKeyStore(keys), WithClock(clock)},
+			err:   ErrTokenNotYetValid,
+		},
+		{
+			name:   "invalid exp",
+			token:  proveMockClaims(t, map[string]interface{}{"exp": "tomorrow"}),
+			opts:   []VerifierOption{WithKeyStore(keys), WithClock(clock)},
+			errMsg: "iden3/go-jwz: claim exp must be a number",
+		},
+		{
+			name:   "missing proof",
+			token:  authV2Parts[0] + "." + authV2Parts[1] + ".",
+			opts:   []VerifierOption{WithKeyStore(KeyStoreMap{AuthV2Groth16Alg: []byte("{}")})},
+			errMsg: "iden3/go-jwz: missing proof in JWZ message",
+		},
+		{
+			name:   "proof without proof data",
+			token:  valid[:strings.LastIndex(valid, ".")+1] + base64.RawURLEncoding.EncodeToString([]byte(`{"pub_signals":["1"]}`)),
+			opts:   []VerifierOption{WithKeyStore(keys)},
+			errMsg: "iden3/go-jwz: missing proof in JWZ message",
+		},
+		{
+			name:   "state is not resolved",
+			token:  valid,
+			opts:   []VerifierOption{WithKeyStore(keys), WithStateResolver(&mockStateResolver{errors.New("unknown state")})},
+			errMsg: "unknown state",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewVerifier(tt.opts...).Verify(context.Background(), tt.token)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+			} else {
+				assert.EqualError(t, err, tt.errMsg)
+			}
+		})
+	}
+}
+
+// senderProvingMethod proves message hash along with sender id, as auth circuits do
+type senderProvingMethod struct {
+	mockProvingMethod
+}
+
+func (m *senderProvingMethod) Verify(messageHash []byte, proof *types.ZKProof, _ []byte) error {
+	if len(proof.PubSignals) != 2 || proof.PubSignals[0] != new(big.Int).SetBytes(messageHash).String() {
+		return errors.New("challenge is not equal to message hash")
+	}
+	return nil
+}
+
+func (m *senderProvingMethod) Prove(inputs, _, _ []byte) (*types.ZKProof, error) {
+	var pubSignals []string
+	if err := json.Unmarshal(inputs, &pubSignals); err != nil {
+		return nil, err
+	}
+	return &types.ZKProof{Proof: &types.ProofData{Protocol: "mock"}, PubSignals: pubSignals}, nil
+}
+
+func TestVerifier_ReplayKey(t *testing.T) {
+	method := &senderProvingMethod{mockProvingMethod{mockAlg}}
+	registry := NewRegistry()
+	assert.NoError(t, registry.Register(mockAlg, func() ProvingMethod { return method }))
+
+	proveBy := func(sender string) string {
+		token, err := NewBuilder().
+			Method(method).
+			Payload([]byte("mymessage")).
+			InputsPreparer(func(hash []byte, _ circuits.CircuitID) ([]byte, error) {
+				return json.Marshal([]string{new(big.Int).SetBytes(hash).String(), sender})
+			}).
+			Prove(context.Background(), ProvingKeys{})
+		assert.NoError(t, err)
+		tokenString, err := token.CompactSerialize()
+		assert.NoError(t, err)
+		return tokenString
+	}
+
+	v := NewVerifier(
+		WithKeyStore(KeyStoreMap{mockAlg: []byte("{}")}),
+		WithParseOptions(WithRegistry(registry)),
+		WithReplayCache(NewMemoryReplayCache(nil), time.Minute),
+	)
+	alice, bob := proveBy("1"), proveBy("2")
+
+	// the same headers and payload proven by different senders don't collide
+	_, err := v.Verify(context.Background(), alice)
+	assert.NoError(t, err)
+	_, err = v.Verify(context.Background(), bob)
+	assert.NoError(t, err)
+
+	_, err = v.Verify(context.Background(), alice)
+	assert.ErrorIs(t, err, ErrTokenReplayed)
+	// proof is not a part of the key, so re-proven token is replayed as well
+	_, err = v.Verify(context.Background(), proveBy("2"))
+	assert.ErrorIs(t, err, ErrTokenReplayed)
+}
+
+func TestMemoryReplayCache(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	cache := NewMemoryReplayCache(func() time.Time { return now })
+	ctx := context.Background()
+
+	for i, ttl := range []time.Duration{3, 1, 2} {
+		seen, err := cache.CheckAndStore(ctx, string(rune('a'+i)), now.Add(ttl*time.Minute))
+		assert.NoError(t, err)
+		assert.False(t, seen)
+	}
+	seen, err := cache.CheckAndStore(ctx, "b", now.Add(time.Hour))
+	assert.NoError(t, err)
+	assert.True(t, seen)
+
+	// ids are forgotten in order of expiration
+	now = now.Add(90 * time.Second)
+	for id, expected := range map[string]bool{"a": true, "b": false, "c": true} {
+		seen, err = cache.CheckAndStore(ctx, id, now.Add(time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, expected, seen, id)
+	}
+	assert.Len(t, cache.(*memoryReplayCache).queue, 3)
+}